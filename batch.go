@@ -0,0 +1,511 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// errBatchUpload is the error of operations with files in a batch, files can't be encoded in the JSON array payload.
+var errBatchUpload = errors.New("files can't be uploaded in a batched request")
+
+// BatchOperation represents a query or mutation which is sent in a batched request.
+type BatchOperation struct {
+	op        operationType
+	v         any
+	variables map[string]any
+	options   []Option
+	err       error
+}
+
+// NewBatchQuery creates a query operation for the Batch method.
+// q should be a pointer to struct that corresponds to the GraphQL schema.
+func NewBatchQuery(q any, variables map[string]any, options ...Option) *BatchOperation {
+	return &BatchOperation{
+		op:        queryOperation,
+		v:         q,
+		variables: variables,
+		options:   options,
+	}
+}
+
+// NewBatchMutation creates a mutation operation for the Batch method.
+// m should be a pointer to struct that corresponds to the GraphQL schema.
+func NewBatchMutation(m any, variables map[string]any, options ...Option) *BatchOperation {
+	return &BatchOperation{
+		op:        mutationOperation,
+		v:         m,
+		variables: variables,
+		options:   options,
+	}
+}
+
+// Err returns the error of the operation after the batch was executed.
+func (bo *BatchOperation) Err() error {
+	return bo.err
+}
+
+// Batch sends multiple operations in a single HTTP request with a JSON array payload.
+// Each operation goes through the middleware chain with its own span and metrics, and is batched when it reaches the end of the chain.
+// Operations which are answered by a middleware, e.g. a cached response, aren't sent.
+// The response of each operation is decoded into its own struct, errors of each operation are returned by the Err method.
+// The returned error is only set if the whole batch request failed.
+func (c *Client) Batch(ctx context.Context, operations ...*BatchOperation) error {
+	if len(operations) == 0 {
+		return nil
+	}
+
+	queries := make([]string, len(operations))
+	optionOutputs := make([]*constructOptionsOutput, len(operations))
+
+	for i, operation := range operations {
+		query, optionsOutput, err := c.buildQueryAndOptions(
+			operation.op,
+			operation.v,
+			operation.variables,
+			operation.options...,
+		)
+		if err != nil {
+			return err
+		}
+
+		if len(findUploads(operation.variables)) > 0 {
+			return Errors{newError(ErrGraphQLEncode, errBatchUpload)}
+		}
+
+		queries[i] = query
+		optionOutputs[i] = optionsOutput
+	}
+
+	batcher := newExplicitBatcher(c, len(operations))
+
+	var wg sync.WaitGroup
+
+	for i, operation := range operations {
+		wg.Add(1)
+
+		go func(i int, operation *BatchOperation) {
+			defer wg.Done()
+
+			member := &batchMember{batcher: batcher, index: i}
+			resp := c.doRequest(
+				context.WithValue(ctx, batchMemberKey{}, member),
+				operation.op,
+				queries[i],
+				operation.variables,
+				optionOutputs[i],
+			)
+			batcher.finish(member)
+
+			operation.err = c.processResponse(operation.v, resp, optionOutputs[i])
+		}(i, operation)
+	}
+
+	wg.Wait()
+
+	if errs := batcher.err(); len(errs) > 0 {
+		return errs
+	}
+
+	if ctx.Err() != nil {
+		return Errors{newError(ErrRequestError, ctx.Err())}
+	}
+
+	return nil
+}
+
+// doBatchRequest sends the payloads in a single request and returns the result of each operation in order.
+func (c *Client) doBatchRequest(
+	ctx context.Context,
+	op operationType,
+	payloads []GraphQLRequestPayload,
+	header http.Header,
+) ([]*rawGraphQLResult, Errors) {
+	var buf bytes.Buffer
+
+	err := json.NewEncoder(&buf).Encode(payloads)
+	if err != nil {
+		return nil, Errors{newError(ErrGraphQLEncode, err)}
+	}
+
+	resp := c.doHttpRequest(ctx, graphqlHTTPRequest{
+		method:      http.MethodPost,
+		url:         c.url,
+		contentType: "application/json",
+		header:      header,
+		body:        bytes.NewReader(buf.Bytes()),
+		batch:       true,
+		op:          op,
+	})
+	if len(resp.Errors) > 0 {
+		return nil, resp.Errors
+	}
+
+	if len(resp.batch) != len(payloads) {
+		return nil, Errors{newError(ErrJsonDecode, fmt.Errorf(
+			"expected %d results in the batch response, got %d",
+			len(payloads),
+			len(resp.batch),
+		))}
+	}
+
+	results := make([]*rawGraphQLResult, len(resp.batch))
+
+	for i := range resp.batch {
+		result := resp.batch[i]
+		result.decoded = true
+		result.request = resp.request
+		result.requestBody = resp.requestBody
+		result.response = resp.response
+		result.responseBody = resp.responseBody
+
+		if len(result.Data) == 0 {
+			result.Data = nil
+		}
+
+		if len(result.Extensions) == 0 {
+			result.Extensions = nil
+		}
+
+		results[i] = &result
+	}
+
+	return results, nil
+}
+
+// decodeBatchResponse decodes the JSON array response of a batched request.
+// Servers which don't support batching usually reply a single object with errors instead.
+func decodeBatchResponse(r io.Reader, out *rawGraphQLResult) error {
+	var raw json.RawMessage
+
+	err := json.NewDecoder(r).Decode(&raw)
+	if err != nil {
+		return err
+	}
+
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		return json.Unmarshal(trimmed, &out.batch)
+	}
+
+	return json.Unmarshal(trimmed, out)
+}
+
+// queryBatcher collects query operations which are issued within a time window,
+// and sends them in a single batched request.
+// The explicit batcher of the Batch method is sent once, when every operation has reached it or left the middleware chain.
+type queryBatcher struct {
+	client       *Client
+	window       time.Duration
+	maxBatchSize int
+	explicit     bool
+
+	mutex   sync.Mutex
+	pending []*batchCall
+	timer   *time.Timer
+	flushed bool
+	// the error of the whole batch request of the explicit batcher
+	errs Errors
+}
+
+// batchCall is a pending operation of the query batcher.
+type batchCall struct {
+	ctx     context.Context //nolint:containedctx
+	op      operationType
+	payload GraphQLRequestPayload
+	header  http.Header
+	member  *batchMember
+	result  *rawGraphQLResult
+	done    chan struct{}
+	// the callers of the flushed batch, nil while the call is pending
+	group *batchGroup
+}
+
+// batchGroup counts the callers which wait for the batched request,
+// so the request is canceled when every caller has left.
+type batchGroup struct {
+	ctx     context.Context //nolint:containedctx
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// newBatchContext creates the context of the request which is shared by the callers.
+// It isn't canceled by a single caller. Attempts are recorded in the metrics and the operation span of every caller,
+// and attempt spans link to the operation spans of the callers.
+func newBatchContext(ctxs []context.Context) (context.Context, context.CancelFunc) {
+	callers := &sharedCallers{}
+	for _, ctx := range ctxs {
+		callers.add(ctx)
+	}
+
+	return context.WithCancel(withSharedCallers(context.Background(), callers))
+}
+
+// batchMember is an operation of the Batch method, which is passed through the middleware chain in the context.
+type batchMember struct {
+	batcher *queryBatcher
+	// the position of the operation in the batch
+	index int
+	// the operation has reached the batcher, guarded by the mutex of the batcher
+	arrived bool
+}
+
+// batchMemberKey is the context key of the batch member.
+type batchMemberKey struct{}
+
+// getBatchMember returns the operation of the Batch method in the context, nil if the operation isn't explicitly batched.
+func getBatchMember(ctx context.Context) *batchMember {
+	member, _ := ctx.Value(batchMemberKey{}).(*batchMember)
+
+	return member
+}
+
+func newQueryBatcher(client *Client) *queryBatcher {
+	return &queryBatcher{
+		client:       client,
+		window:       client.batchWindow,
+		maxBatchSize: client.maxBatchSize,
+	}
+}
+
+// newExplicitBatcher creates the batcher of the operations of the Batch method.
+func newExplicitBatcher(client *Client, size int) *queryBatcher {
+	return &queryBatcher{
+		client:       client,
+		maxBatchSize: size,
+		explicit:     true,
+	}
+}
+
+// do queues the call and waits for the result of the batch.
+// The caller stops waiting if its context is canceled. The pending call is removed from the queue,
+// and the batch request is canceled when every caller has left.
+func (qb *queryBatcher) do(call *batchCall) *rawGraphQLResult {
+	ctx := call.ctx
+	call.done = make(chan struct{})
+
+	qb.mutex.Lock()
+	if call.member != nil {
+		call.member.arrived = true
+	}
+
+	// the explicit batch is already sent, e.g. a middleware calls the next handler again.
+	if qb.explicit && qb.flushed {
+		qb.mutex.Unlock()
+
+		return qb.client.sendRequest(ctx, call.op, call.payload, call.header)
+	}
+
+	qb.pending = append(qb.pending, call)
+
+	switch {
+	case qb.maxBatchSize > 0 && len(qb.pending) >= qb.maxBatchSize:
+		calls := qb.takePending()
+		qb.mutex.Unlock()
+
+		go qb.flush(calls)
+	case len(qb.pending) == 1 && !qb.explicit:
+		qb.timer = time.AfterFunc(qb.window, qb.flushPending)
+		qb.mutex.Unlock()
+	default:
+		qb.mutex.Unlock()
+	}
+
+	select {
+	case <-call.done:
+		return call.result
+	case <-ctx.Done():
+		qb.leave(call)
+
+		return &rawGraphQLResult{
+			Errors: Errors{newError(ErrRequestError, ctx.Err())},
+		}
+	}
+}
+
+// leave removes the pending call from the queue, or cancels the batch request if every caller has left.
+func (qb *queryBatcher) leave(call *batchCall) {
+	qb.mutex.Lock()
+
+	if call.group != nil {
+		call.group.waiters--
+		if call.group.waiters == 0 {
+			call.group.cancel()
+		}
+
+		qb.mutex.Unlock()
+
+		return
+	}
+
+	for i, pending := range qb.pending {
+		if pending == call {
+			qb.pending = append(qb.pending[:i], qb.pending[i+1:]...)
+
+			break
+		}
+	}
+
+	if len(qb.pending) == 0 && qb.timer != nil {
+		qb.timer.Stop()
+		qb.timer = nil
+	}
+
+	var calls []*batchCall
+	if qb.explicit {
+		calls = qb.shrink()
+	}
+
+	qb.mutex.Unlock()
+
+	if len(calls) > 0 {
+		go qb.flush(calls)
+	}
+}
+
+// finish is called when the operation of the Batch method leaves the middleware chain.
+// The batch doesn't wait for operations which haven't reached the batcher, e.g. answered by a middleware.
+func (qb *queryBatcher) finish(member *batchMember) {
+	qb.mutex.Lock()
+
+	var calls []*batchCall
+	if !member.arrived {
+		calls = qb.shrink()
+	}
+
+	qb.mutex.Unlock()
+
+	qb.flush(calls)
+}
+
+// shrink reduces the size of the explicit batch by one operation,
+// and returns the pending calls if they are the rest of the batch. The caller must hold the lock.
+func (qb *queryBatcher) shrink() []*batchCall {
+	qb.maxBatchSize--
+
+	if qb.flushed || len(qb.pending) == 0 || len(qb.pending) < qb.maxBatchSize {
+		return nil
+	}
+
+	return qb.takePending()
+}
+
+// err returns the error of the whole batch request of the explicit batcher.
+func (qb *queryBatcher) err() Errors {
+	qb.mutex.Lock()
+	defer qb.mutex.Unlock()
+
+	return qb.errs
+}
+
+// takePending returns pending calls in the group of their batch request, and resets the queue.
+// The caller must hold the lock.
+func (qb *queryBatcher) takePending() []*batchCall {
+	if qb.timer != nil {
+		qb.timer.Stop()
+		qb.timer = nil
+	}
+
+	calls := qb.pending
+	qb.pending = nil
+	qb.flushed = true
+
+	if len(calls) == 0 {
+		return nil
+	}
+
+	callers := make([]context.Context, len(calls))
+	for i, call := range calls {
+		callers[i] = call.ctx
+	}
+
+	ctx, cancel := newBatchContext(callers)
+	group := &batchGroup{
+		ctx:     ctx,
+		waiters: len(calls),
+		cancel:  cancel,
+	}
+
+	for _, call := range calls {
+		call.group = group
+	}
+
+	return calls
+}
+
+func (qb *queryBatcher) flushPending() {
+	qb.mutex.Lock()
+	calls := qb.takePending()
+	qb.mutex.Unlock()
+
+	qb.flush(calls)
+}
+
+// flush sends the calls and routes the results back to their callers.
+func (qb *queryBatcher) flush(calls []*batchCall) {
+	if len(calls) == 0 {
+		return
+	}
+
+	ctx := calls[0].group.ctx
+	defer calls[0].group.cancel()
+
+	if len(calls) == 1 && !qb.explicit {
+		// a single operation doesn't need to be batched.
+		calls[0].result = qb.client.sendRequest(ctx, calls[0].op, calls[0].payload, calls[0].header)
+		close(calls[0].done)
+
+		return
+	}
+
+	// operations of the Batch method are sent in order, whichever reaches the batcher first.
+	if qb.explicit {
+		sort.SliceStable(calls, func(i, j int) bool {
+			return calls[i].member.index < calls[j].member.index
+		})
+	}
+
+	// batches which contain mutations aren't retried.
+	op := queryOperation
+	payloads := make([]GraphQLRequestPayload, len(calls))
+	header := http.Header{}
+
+	for i, call := range calls {
+		payloads[i] = call.payload
+
+		if call.op == mutationOperation {
+			op = mutationOperation
+		}
+
+		// the header of the first operation which sets it is sent.
+		for key, values := range call.header {
+			if _, ok := header[key]; !ok {
+				header[key] = values
+			}
+		}
+	}
+
+	results, errs := qb.client.doBatchRequest(ctx, op, payloads, header)
+	if len(errs) > 0 && qb.explicit {
+		qb.mutex.Lock()
+		qb.errs = errs
+		qb.mutex.Unlock()
+	}
+
+	for i, call := range calls {
+		if len(errs) > 0 {
+			call.result = &rawGraphQLResult{Errors: errs}
+		} else {
+			call.result = results[i]
+		}
+
+		close(call.done)
+	}
+}
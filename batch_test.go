@@ -0,0 +1,463 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+func TestClient_Batch(t *testing.T) {
+	var requests int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		var payloads []graphql.GraphQLRequestPayload
+		if err := json.NewDecoder(req.Body).Decode(&payloads); err != nil {
+			t.Fatal(err)
+		}
+		if len(payloads) != 3 {
+			t.Fatalf("got %d payloads, want: 3", len(payloads))
+		}
+		if got, want := payloads[0].Query, "query ($id:ID!){user(id: $id){name}}"; got != want {
+			t.Errorf("got payloads[0].Query: %q, want: %q", got, want)
+		}
+		if got, want := payloads[1].OperationName, "GetViewer"; got != want {
+			t.Errorf("got payloads[1].OperationName: %q, want: %q", got, want)
+		}
+		if got, want := payloads[2].Query, "mutation{addStar{starrable{id}}}"; got != want {
+			t.Errorf("got payloads[2].Query: %q, want: %q", got, want)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `[
+			{"data": {"user": {"name": "Gopher"}}},
+			{"data": {"viewer": {"login": "gopher"}}, "extensions": {"cost": 1}},
+			{"data": null, "errors": [{"message": "Not authorized", "path": ["addStar"]}]}
+		]`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var userQuery struct {
+		User struct {
+			Name string
+		} `graphql:"user(id: $id)"`
+	}
+	var viewerQuery struct {
+		Viewer struct {
+			Login string
+		}
+	}
+	var starMutation struct {
+		AddStar struct {
+			Starrable struct {
+				ID graphql.ID
+			}
+		}
+	}
+	var ext struct {
+		Cost int `json:"cost"`
+	}
+
+	userOp := graphql.NewBatchQuery(&userQuery, map[string]any{"id": graphql.ID("1")})
+	viewerOp := graphql.NewBatchQuery(&viewerQuery, nil, graphql.OperationName("GetViewer"), graphql.BindExtensions(&ext))
+	starOp := graphql.NewBatchMutation(&starMutation, nil)
+
+	if err := client.Batch(context.Background(), userOp, viewerOp, starOp); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want: 1", requests)
+	}
+
+	if userOp.Err() != nil || userQuery.User.Name != "Gopher" {
+		t.Errorf("got user: %q, err: %v", userQuery.User.Name, userOp.Err())
+	}
+	if viewerOp.Err() != nil || viewerQuery.Viewer.Login != "gopher" || ext.Cost != 1 {
+		t.Errorf("got viewer: %q, cost: %d, err: %v", viewerQuery.Viewer.Login, ext.Cost, viewerOp.Err())
+	}
+
+	var errs graphql.Errors
+	if !errors.As(starOp.Err(), &errs) || len(errs) != 1 || errs[0].Message != "Not authorized" {
+		t.Errorf("got mutation error: %v, want: Not authorized", starOp.Err())
+	}
+}
+
+func TestClient_Batch_middleware(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var payloads []graphql.GraphQLRequestPayload
+		if err := json.NewDecoder(req.Body).Decode(&payloads); err != nil {
+			t.Error(err)
+			return
+		}
+		if len(payloads) != 2 || payloads[0].OperationName != "GetUser" || payloads[1].OperationName != "GetOrg" {
+			t.Errorf("got payloads: %+v, want: GetUser and GetOrg", payloads)
+		}
+		if got := req.Header.Get("X-Tenant"); got != "acme" {
+			t.Errorf("got X-Tenant: %q, want: acme", got)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `[{"data": {"user": {"name": "Gopher"}}}, {"data": {"org": {"name": "Hasura"}}}]`)
+	})
+
+	var mu sync.Mutex
+	var operations []string
+	var records []graphql.OperationMetrics
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithMiddleware(func(next graphql.Handler) graphql.Handler {
+			return func(ctx context.Context, req *graphql.Request) *graphql.Response {
+				mu.Lock()
+				operations = append(operations, req.OperationName)
+				mu.Unlock()
+
+				// the viewer is answered by the middleware without being sent.
+				if req.OperationName == "GetViewer" {
+					return &graphql.Response{Data: []byte(`{"viewer": {"login": "gopher"}}`)}
+				}
+
+				req.Header = http.Header{"X-Tenant": {"acme"}}
+
+				return next(ctx, req)
+			}
+		}),
+		graphql.WithMetricsRecorder(metricsRecorderFunc(func(ctx context.Context, metrics graphql.OperationMetrics) {
+			mu.Lock()
+			records = append(records, metrics)
+			mu.Unlock()
+		})),
+	)
+
+	var userQuery struct {
+		User struct {
+			Name string
+		}
+	}
+	var viewerQuery struct {
+		Viewer struct {
+			Login string
+		}
+	}
+	var orgQuery struct {
+		Org struct {
+			Name string
+		}
+	}
+	userOp := graphql.NewBatchQuery(&userQuery, nil, graphql.OperationName("GetUser"))
+	viewerOp := graphql.NewBatchQuery(&viewerQuery, nil, graphql.OperationName("GetViewer"))
+	orgOp := graphql.NewBatchQuery(&orgQuery, nil, graphql.OperationName("GetOrg"))
+
+	if err := client.Batch(context.Background(), userOp, viewerOp, orgOp); err != nil {
+		t.Fatal(err)
+	}
+
+	if userOp.Err() != nil || userQuery.User.Name != "Gopher" {
+		t.Errorf("got user: %q, err: %v", userQuery.User.Name, userOp.Err())
+	}
+	if viewerOp.Err() != nil || viewerQuery.Viewer.Login != "gopher" {
+		t.Errorf("got viewer: %q, err: %v", viewerQuery.Viewer.Login, viewerOp.Err())
+	}
+	if orgOp.Err() != nil || orgQuery.Org.Name != "Hasura" {
+		t.Errorf("got org: %q, err: %v", orgQuery.Org.Name, orgOp.Err())
+	}
+
+	// every operation goes through the middleware chain and is recorded in the metrics.
+	if len(operations) != 3 {
+		t.Errorf("got operations: %v, want: 3", operations)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want: 3", len(records))
+	}
+	for _, got := range records {
+		want := 1
+		if got.OperationName == "GetViewer" {
+			want = 0
+		}
+		if got.Attempts != want {
+			t.Errorf("got %d attempts of %s, want: %d", got.Attempts, got.OperationName, want)
+		}
+	}
+}
+
+func TestClient_Batch_notSupported(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"errors": [{"message": "Operation batching is not supported"}]}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Viewer struct {
+			Login string
+		}
+	}
+	op := graphql.NewBatchQuery(&q, nil)
+
+	err := client.Batch(context.Background(), op, graphql.NewBatchQuery(&q, nil))
+	if err == nil {
+		t.Fatal("got error: nil, want: non-nil")
+	}
+	if got, want := err.Error(), "Message: Operation batching is not supported, Locations: [], Extensions: map[], Path: []"; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+	if op.Err() == nil {
+		t.Error("got operation error: nil, want: non-nil")
+	}
+}
+
+func TestClientOption_WithBatchWindow(t *testing.T) {
+	var mu sync.Mutex
+	var batchSizes []int
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var payloads []graphql.GraphQLRequestPayload
+		if err := json.NewDecoder(req.Body).Decode(&payloads); err != nil {
+			t.Error(err)
+			return
+		}
+		mu.Lock()
+		batchSizes = append(batchSizes, len(payloads))
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, "[")
+		for i, payload := range payloads {
+			if i > 0 {
+				mustWrite(w, ",")
+			}
+			mustWrite(w, fmt.Sprintf(`{"data": {"user": {"name": %q}}}`, payload.Variables["id"]))
+		}
+		mustWrite(w, "]")
+	})
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithBatchWindow(50*time.Millisecond, 3),
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+
+			var q struct {
+				User struct {
+					Name string
+				} `graphql:"user(id: $id)"`
+			}
+			if err := client.Query(context.Background(), &q, map[string]any{"id": id}); err != nil {
+				t.Error(err)
+				return
+			}
+			if q.User.Name != id {
+				t.Errorf("got q.User.Name: %q, want: %q", q.User.Name, id)
+			}
+		}(fmt.Sprint(i))
+	}
+	wg.Wait()
+
+	// 3 queries are flushed when the batch is full, the remaining ones are flushed after the window.
+	mu.Lock()
+	defer mu.Unlock()
+	if len(batchSizes) != 2 || batchSizes[0]+batchSizes[1] != 5 {
+		t.Errorf("got batch sizes: %v, want: [3 2]", batchSizes)
+	}
+}
+
+func TestClientOption_WithBatchWindow_deadline(t *testing.T) {
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		close(started)
+		<-req.Context().Done()
+		close(canceled)
+	})
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithBatchWindow(time.Second, 2),
+	)
+
+	type query struct {
+		User struct {
+			Name string
+		} `graphql:"user(id: $id)"`
+	}
+
+	// the first caller times out while the other one still waits for the batch.
+	firstCtx, cancelFirst := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelFirst()
+	firstDone := make(chan error, 1)
+	go func() {
+		var q query
+		firstDone <- client.Query(firstCtx, &q, map[string]any{"id": "1"})
+	}()
+
+	secondCtx, cancelSecond := context.WithCancel(context.Background())
+	secondDone := make(chan error, 1)
+	go func() {
+		var q query
+		secondDone <- client.Query(secondCtx, &q, map[string]any{"id": "2"})
+	}()
+	<-started
+
+	if err := <-firstDone; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error: %v, want: context deadline exceeded", err)
+	}
+
+	// the batch request isn't canceled by a single caller.
+	select {
+	case <-canceled:
+		t.Fatal("the batch request is canceled by the first caller")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// the request is canceled when every caller has left.
+	cancelSecond()
+	if err := <-secondDone; !errors.Is(err, context.Canceled) {
+		t.Errorf("got error: %v, want: context canceled", err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("the batch request isn't canceled")
+	}
+}
+
+func TestClient_Batch_upload(t *testing.T) {
+	var requests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `[{"data": {"uploadAvatar": {"ok": true}}}]`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var m struct {
+		UploadAvatar struct {
+			OK bool
+		} `graphql:"uploadAvatar(file: $file)"`
+	}
+	op := graphql.NewBatchMutation(&m, map[string]any{
+		"file": graphql.Upload{FileName: "avatar.png", File: strings.NewReader("hello")},
+	})
+
+	// files can't be encoded in the JSON array payload.
+	err := client.Batch(context.Background(), op)
+	var errs graphql.Errors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Code() != graphql.ErrGraphQLEncode {
+		t.Errorf("got error: %v, want: encode error", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 0 {
+		t.Errorf("got %d requests, want: 0", got)
+	}
+}
+
+func TestClientOption_WithBatchWindow_upload(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if err := req.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("got a request which isn't multipart: %s", err)
+			return
+		}
+		file, _, err := req.FormFile("0")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if content, _ := io.ReadAll(file); string(content) != "hello" {
+			t.Errorf("got file: %q, want: hello", content)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"avatar": {"size": 5}}}`)
+	})
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithBatchWindow(time.Second, 2),
+	)
+
+	// the query with a file isn't batched, it's sent alone without waiting for the window.
+	var q struct {
+		Avatar struct {
+			Size int
+		} `graphql:"avatar(file: $file)"`
+	}
+	variables := map[string]any{
+		"file": graphql.Upload{FileName: "avatar.png", File: strings.NewReader("hello")},
+	}
+	start := time.Now()
+	if err := client.Query(context.Background(), &q, variables); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("got elapsed: %s, want the query to be sent without waiting for the window", elapsed)
+	}
+	if q.Avatar.Size != 5 {
+		t.Errorf("got size: %d, want: 5", q.Avatar.Size)
+	}
+}
+
+func TestClientOption_WithBatchWindow_httpGet(t *testing.T) {
+	var requests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if req.Method != http.MethodGet {
+			t.Errorf("got method: %s, want: GET", req.Method)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithBatchWindow(time.Second, 2),
+		graphql.WithHTTPGet(0),
+	)
+
+	// the queries are sent with GET without waiting for the window.
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var q struct {
+				Viewer struct {
+					Login string
+				}
+			}
+			if err := client.Query(context.Background(), &q, nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("got elapsed: %s, want the queries to be sent without waiting for the window", elapsed)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("got %d requests, want: 2", got)
+	}
+}
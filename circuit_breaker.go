@@ -0,0 +1,293 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var errCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of the circuit breaker.
+type CircuitState uint8
+
+const (
+	// CircuitClosed lets all requests through and counts consecutive failures.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects all requests until the open timeout elapses.
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of probe requests through.
+	// The circuit is closed if the probes succeed, or opened again if one of them fails.
+	CircuitHalfOpen
+)
+
+// String returns the name of the circuit state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker stops sending requests to the GraphQL endpoint after many consecutive failures,
+// so operations fail fast instead of waiting through the full retry schedule while the server degrades.
+// Rejected requests return an error with the ErrCircuitOpen code.
+//
+// The breaker is shared by all copies of the client, and can be shared between clients of the same endpoint.
+type CircuitBreaker struct {
+	// FailureThreshold is the number of consecutive failed attempts which opens the circuit. Default 5.
+	FailureThreshold int
+	// OpenTimeout is the duration the circuit stays open before probe requests are allowed. Default 30 seconds.
+	OpenTimeout time.Duration
+	// HalfOpenMaxRequests is the number of probe requests in the half-open state. Default 1.
+	HalfOpenMaxRequests int
+	// HTTPStatus is the list of HTTP statuses which are counted as failures. Default: 429, 500, 502, 503, 504.
+	// Requests which fail without a response are always failures.
+	HTTPStatus []int
+	// IsGraphQLFailure checks if the GraphQL errors of a successful HTTP response are counted as failures.
+	// If nil, the RetryOnGraphQLError function of the client's BackoffRetryPolicy is used if it exists.
+	IsGraphQLFailure func(errs Errors) bool
+	// OnStateChange is called when the circuit state changes, e.g. to alert when the circuit opens.
+	OnStateChange func(from CircuitState, to CircuitState)
+
+	mu               sync.Mutex
+	state            CircuitState
+	failures         int
+	openedAt         time.Time
+	halfOpenRequests int
+	halfOpenSuccess  int
+}
+
+// NewCircuitBreaker creates a circuit breaker with default settings.
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		FailureThreshold:    failureThreshold,
+		OpenTimeout:         openTimeout,
+		HalfOpenMaxRequests: 1,
+		HTTPStatus: []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// WithCircuitBreaker creates an option to guard the GraphQL endpoint with the circuit breaker.
+// Every request attempt, including retries, is checked and recorded by the breaker.
+func WithCircuitBreaker(breaker *CircuitBreaker) ClientOption {
+	return func(c *Client) {
+		c.circuitBreaker = breaker
+	}
+}
+
+// State returns the current state of the circuit.
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.currentState()
+}
+
+// Reset closes the circuit and clears the failure count.
+func (cb *CircuitBreaker) Reset() {
+	cb.mu.Lock()
+	from := cb.currentState()
+	cb.setState(CircuitClosed)
+	cb.mu.Unlock()
+
+	cb.notify(from, CircuitClosed)
+}
+
+// allow checks if the request attempt can be sent.
+func (cb *CircuitBreaker) allow() error {
+	cb.mu.Lock()
+	from := cb.state
+	state := cb.currentState()
+
+	if state == CircuitOpen {
+		cb.mu.Unlock()
+
+		return errCircuitOpen
+	}
+
+	if state == CircuitHalfOpen {
+		if cb.state != CircuitHalfOpen {
+			cb.setState(CircuitHalfOpen)
+		}
+
+		if cb.halfOpenRequests >= cb.halfOpenMaxRequests() {
+			cb.mu.Unlock()
+			cb.notify(from, state)
+
+			return errCircuitOpen
+		}
+
+		cb.halfOpenRequests++
+	}
+
+	cb.mu.Unlock()
+	cb.notify(from, state)
+
+	return nil
+}
+
+// record counts the result of the request attempt.
+func (cb *CircuitBreaker) record(failure bool) {
+	cb.mu.Lock()
+	from := cb.state
+	to := from
+
+	switch from {
+	case CircuitClosed:
+		if !failure {
+			cb.failures = 0
+
+			break
+		}
+
+		cb.failures++
+		if cb.failures >= cb.failureThreshold() {
+			to = CircuitOpen
+		}
+	case CircuitHalfOpen:
+		if failure {
+			to = CircuitOpen
+
+			break
+		}
+
+		cb.halfOpenSuccess++
+		if cb.halfOpenSuccess >= cb.halfOpenMaxRequests() {
+			to = CircuitClosed
+		}
+	case CircuitOpen:
+		// results of requests which were sent before the circuit opened are ignored.
+	}
+
+	if to != from {
+		cb.setState(to)
+	}
+
+	cb.mu.Unlock()
+	cb.notify(from, to)
+}
+
+// release frees the probe slot of the request attempt which was canceled by the caller,
+// the result of the attempt doesn't say anything about the server.
+func (cb *CircuitBreaker) release() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == CircuitHalfOpen && cb.halfOpenRequests > 0 {
+		cb.halfOpenRequests--
+	}
+}
+
+// currentState returns the state of the circuit, the open circuit becomes half-open after the timeout.
+func (cb *CircuitBreaker) currentState() CircuitState {
+	if cb.state == CircuitOpen && time.Since(cb.openedAt) >= cb.openTimeout() {
+		return CircuitHalfOpen
+	}
+
+	return cb.state
+}
+
+func (cb *CircuitBreaker) setState(state CircuitState) {
+	cb.state = state
+	cb.failures = 0
+	cb.halfOpenRequests = 0
+	cb.halfOpenSuccess = 0
+
+	if state == CircuitOpen {
+		cb.openedAt = time.Now()
+	}
+}
+
+func (cb *CircuitBreaker) notify(from CircuitState, to CircuitState) {
+	if from != to && cb.OnStateChange != nil {
+		cb.OnStateChange(from, to)
+	}
+}
+
+// isFailure checks if the result of the request attempt is counted as a failure.
+func (cb *CircuitBreaker) isFailure(resp *http.Response, errs Errors, retryPolicy RetryPolicy) bool {
+	switch {
+	case resp == nil:
+		return len(errs) > 0
+	case resp.StatusCode >= 400:
+		for _, status := range cb.HTTPStatus {
+			if status == resp.StatusCode {
+				return true
+			}
+		}
+
+		return false
+	case len(errs) == 0:
+		return false
+	case cb.IsGraphQLFailure != nil:
+		return cb.IsGraphQLFailure(errs)
+	default:
+		policy, ok := retryPolicy.(*BackoffRetryPolicy)
+
+		return ok && policy.RetryOnGraphQLError != nil && policy.RetryOnGraphQLError(errs)
+	}
+}
+
+func (cb *CircuitBreaker) failureThreshold() int {
+	if cb.FailureThreshold <= 0 {
+		return 5
+	}
+
+	return cb.FailureThreshold
+}
+
+func (cb *CircuitBreaker) openTimeout() time.Duration {
+	if cb.OpenTimeout <= 0 {
+		return 30 * time.Second
+	}
+
+	return cb.OpenTimeout
+}
+
+func (cb *CircuitBreaker) halfOpenMaxRequests() int {
+	if cb.HalfOpenMaxRequests <= 0 {
+		return 1
+	}
+
+	return cb.HalfOpenMaxRequests
+}
+
+// allowAttempt checks the circuit breaker of the client before the request attempt.
+func (c *Client) allowAttempt() error {
+	if c.circuitBreaker == nil {
+		return nil
+	}
+
+	return c.circuitBreaker.allow()
+}
+
+// recordAttemptResult records the result of the request attempt to the circuit breaker of the client.
+// Attempts which are canceled by the caller aren't counted.
+func (c *Client) recordAttemptResult(ctx context.Context, resp *http.Response, errs Errors) {
+	if c.circuitBreaker == nil {
+		return
+	}
+
+	if ctx.Err() != nil {
+		c.circuitBreaker.release()
+
+		return
+	}
+
+	c.circuitBreaker.record(c.circuitBreaker.isFailure(resp, errs, c.retryPolicy))
+}
@@ -0,0 +1,182 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+func hasErrorCode(err error, code string) bool {
+	var errs graphql.Errors
+	if !errors.As(err, &errs) {
+		return false
+	}
+
+	for _, e := range errs {
+		if e.Extensions["code"] == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestClientOption_WithCircuitBreaker(t *testing.T) {
+	var requests int32
+	var healthy atomic.Bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if !healthy.Load() {
+			http.Error(w, "temporary error", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+
+	var mu sync.Mutex
+	var transitions []string
+	breaker := graphql.NewCircuitBreaker(2, 100*time.Millisecond)
+	breaker.OnStateChange = func(from graphql.CircuitState, to graphql.CircuitState) {
+		mu.Lock()
+		defer mu.Unlock()
+		transitions = append(transitions, from.String()+"->"+to.String())
+	}
+
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithCircuitBreaker(breaker),
+	)
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		err := client.Query(context.Background(), &q, nil)
+		if err == nil || hasErrorCode(err, graphql.ErrCircuitOpen) {
+			t.Fatalf("got error: %v, want: 503", err)
+		}
+	}
+	if state := breaker.State(); state != graphql.CircuitOpen {
+		t.Fatalf("got state: %s, want: open", state)
+	}
+
+	// the open circuit fails fast without sending the request.
+	err := client.Query(context.Background(), &q, nil)
+	if !hasErrorCode(err, graphql.ErrCircuitOpen) {
+		t.Fatalf("got error: %v, want: %s", err, graphql.ErrCircuitOpen)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("got %d requests, want: 2", got)
+	}
+
+	// the probe request closes the circuit after the open timeout.
+	time.Sleep(150 * time.Millisecond)
+	if state := breaker.State(); state != graphql.CircuitHalfOpen {
+		t.Fatalf("got state: %s, want: half-open", state)
+	}
+	healthy.Store(true)
+	if err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+	if state := breaker.State(); state != graphql.CircuitClosed {
+		t.Errorf("got state: %s, want: closed", state)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []string{"closed->open", "open->half-open", "half-open->closed"}
+	if len(transitions) != len(expected) {
+		t.Fatalf("got transitions: %v, want: %v", transitions, expected)
+	}
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Errorf("got transitions: %v, want: %v", transitions, expected)
+		}
+	}
+}
+
+func TestClientOption_WithCircuitBreaker_halfOpenFailure(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "temporary error", http.StatusBadGateway)
+	})
+
+	breaker := graphql.NewCircuitBreaker(1, 50*time.Millisecond)
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithCircuitBreaker(breaker),
+	)
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+
+	_ = client.Query(context.Background(), &q, nil)
+	time.Sleep(100 * time.Millisecond)
+
+	// the failed probe opens the circuit again.
+	if err := client.Query(context.Background(), &q, nil); hasErrorCode(err, graphql.ErrCircuitOpen) {
+		t.Fatalf("got error: %v, want the probe request to be sent", err)
+	}
+	if state := breaker.State(); state != graphql.CircuitOpen {
+		t.Errorf("got state: %s, want: open", state)
+	}
+
+	breaker.Reset()
+	if state := breaker.State(); state != graphql.CircuitClosed {
+		t.Errorf("got state: %s, want: closed", state)
+	}
+}
+
+func TestClientOption_WithCircuitBreaker_stopRetries(t *testing.T) {
+	var requests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"errors": [{"message": "database is unavailable"}]}`)
+	})
+
+	policy := graphql.NewBackoffRetryPolicy(5)
+	policy.BaseDelay = time.Millisecond
+	policy.RetryOnGraphQLError = func(errs graphql.Errors) bool {
+		return len(errs) == 1 && errs[0].Message == "database is unavailable"
+	}
+
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRetryPolicy(policy),
+		graphql.WithCircuitBreaker(graphql.NewCircuitBreaker(3, time.Minute)),
+	)
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+
+	// the retryable GraphQL errors are counted as failures, retries stop when the circuit opens.
+	err := client.Query(context.Background(), &q, nil)
+	if !hasErrorCode(err, graphql.ErrCircuitOpen) {
+		t.Errorf("got error: %v, want: %s", err, graphql.ErrCircuitOpen)
+	}
+	if got := atomic.LoadInt32(&requests); got != 3 {
+		t.Errorf("got %d requests, want: 3", got)
+	}
+}
@@ -0,0 +1,131 @@
+package graphql
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// default encodings of the Accept-Encoding header which are decompressed by the client.
+const defaultAcceptEncoding = "gzip, deflate, zstd"
+
+// WithRequestCompression creates an option to compress JSON request bodies with gzip
+// if the size of the body is at least minSize bytes, e.g. mutations with large variables.
+// The server must support requests with the Content-Encoding: gzip header.
+func WithRequestCompression(minSize int) ClientOption {
+	return func(c *Client) {
+		c.requestCompression = true
+		c.requestCompressionMinSize = minSize
+	}
+}
+
+// WithResponseCompression creates an option to send the Accept-Encoding header with the encodings,
+// default gzip, deflate and zstd if empty.
+// Compressed responses are decompressed transparently, even if the custom Doer doesn't do it.
+func WithResponseCompression(encodings ...string) ClientOption {
+	return func(c *Client) {
+		c.acceptEncoding = defaultAcceptEncoding
+
+		if len(encodings) > 0 {
+			c.acceptEncoding = strings.Join(encodings, ", ")
+		}
+	}
+}
+
+// compressRequestBody compresses the JSON request body with gzip if the request compression is enabled.
+// It returns the body and the content encoding of the request.
+func (c *Client) compressRequestBody(input graphqlHTTPRequest) (io.ReadSeeker, string, error) {
+	if !c.requestCompression || input.method != http.MethodPost || input.contentType != "application/json" {
+		return input.body, "", nil
+	}
+
+	size, err := input.body.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if size < int64(c.requestCompressionMinSize) {
+		return input.body, "", nil
+	}
+
+	if _, err := input.body.Seek(0, io.SeekStart); err != nil {
+		return nil, "", err
+	}
+
+	var buf bytes.Buffer
+
+	writer := gzip.NewWriter(&buf)
+	if _, err := io.Copy(writer, input.body); err != nil {
+		return nil, "", err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return bytes.NewReader(buf.Bytes()), "gzip", nil
+}
+
+// decompressResponseBody wraps the response body with the decompressor of the Content-Encoding header.
+// The Go transport removes the header if it decompressed the body itself.
+// Closing the reader doesn't close the response body.
+func decompressResponseBody(resp *http.Response) (io.ReadCloser, error) {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+
+	switch encoding {
+	case "", "identity":
+		return io.NopCloser(resp.Body), nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(resp.Body)
+	case "deflate":
+		return newDeflateReader(resp.Body)
+	case "zstd":
+		decoder, err := zstd.NewReader(resp.Body, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+
+		return decoder.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", encoding)
+	}
+}
+
+// newDeflateReader decompresses the deflate body.
+// The deflate encoding should be zlib-wrapped, but some servers send the raw deflate stream.
+func newDeflateReader(body io.Reader) (io.ReadCloser, error) {
+	reader := bufio.NewReader(body)
+
+	header, err := reader.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(reader)
+	}
+
+	return flate.NewReader(reader), nil
+}
+
+// readErrorBody reads the decompressed body of the error response.
+func readErrorBody(resp *http.Response) ([]byte, error) {
+	r, err := decompressResponseBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = r.Close()
+	}()
+
+	return io.ReadAll(r)
+}
@@ -0,0 +1,163 @@
+package graphql_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hasura/go-graphql-client"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestClientOption_WithRequestCompression(t *testing.T) {
+	var encodings []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		encodings = append(encodings, req.Header.Get("Content-Encoding"))
+
+		var body io.Reader = req.Body
+		if req.Header.Get("Content-Encoding") == "gzip" {
+			reader, err := gzip.NewReader(req.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body = reader
+		}
+
+		var payload struct {
+			Variables map[string]any `json:"variables"`
+		}
+		if err := json.NewDecoder(body).Decode(&payload); err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, fmt.Sprintf(`{"data": {"importUsers": %d}}`, len(payload.Variables["users"].(string))))
+	})
+
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRequestCompression(1024),
+	)
+
+	var m struct {
+		ImportUsers int64 `graphql:"importUsers(users: $users)"`
+	}
+
+	// small bodies aren't compressed.
+	if err := client.Mutate(context.Background(), &m, map[string]any{"users": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Mutate(context.Background(), &m, map[string]any{"users": strings.Repeat("a", 2048)}); err != nil {
+		t.Fatal(err)
+	}
+	if m.ImportUsers != 2048 {
+		t.Errorf("got: %d, want: 2048", m.ImportUsers)
+	}
+
+	if len(encodings) != 2 || encodings[0] != "" || encodings[1] != "gzip" {
+		t.Errorf("got content encodings: %q, want: [\"\" \"gzip\"]", encodings)
+	}
+}
+
+func TestClientOption_WithResponseCompression(t *testing.T) {
+	const body = `{"data": {"user": {"name": "Gopher"}}}`
+
+	compress := func(encoding string) []byte {
+		var buf bytes.Buffer
+		var writer io.WriteCloser
+		switch encoding {
+		case "gzip":
+			writer = gzip.NewWriter(&buf)
+		case "deflate":
+			writer = zlib.NewWriter(&buf)
+		case "raw-deflate":
+			writer, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+		case "zstd":
+			writer, _ = zstd.NewWriter(&buf)
+		}
+		_, _ = writer.Write([]byte(body))
+		_ = writer.Close()
+
+		return buf.Bytes()
+	}
+
+	for _, encoding := range []string{"gzip", "deflate", "raw-deflate", "zstd"} {
+		t.Run(encoding, func(t *testing.T) {
+			var acceptEncoding string
+			mux := http.NewServeMux()
+			mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+				acceptEncoding = req.Header.Get("Accept-Encoding")
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Content-Encoding", strings.TrimPrefix(encoding, "raw-"))
+				_, _ = w.Write(compress(encoding))
+			})
+
+			client := graphql.NewClient(
+				"/graphql",
+				&http.Client{Transport: localRoundTripper{handler: mux}},
+				graphql.WithResponseCompression(),
+			)
+
+			var q struct {
+				User struct {
+					Name string
+				}
+			}
+			if err := client.Query(context.Background(), &q, nil); err != nil {
+				t.Fatal(err)
+			}
+			if q.User.Name != "Gopher" {
+				t.Errorf("got name: %q, want: Gopher", q.User.Name)
+			}
+			if acceptEncoding != "gzip, deflate, zstd" {
+				t.Errorf("got Accept-Encoding: %q, want: gzip, deflate, zstd", acceptEncoding)
+			}
+		})
+	}
+}
+
+func TestClientOption_WithResponseCompression_errorBody(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		_, _ = writer.Write([]byte("bad gateway"))
+		_ = writer.Close()
+
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write(buf.Bytes())
+	})
+
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithResponseCompression("gzip"),
+	)
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	err := client.Query(context.Background(), &q, nil)
+
+	var errs graphql.Errors
+	var networkErr graphql.NetworkError
+	if !errors.As(err, &errs) || !errors.As(errs[0].Unwrap(), &networkErr) {
+		t.Fatalf("got error: %v, want: NetworkError", err)
+	}
+	if networkErr.Body() != "bad gateway" {
+		t.Errorf("got body: %q, want: bad gateway", networkErr.Body())
+	}
+}
@@ -0,0 +1,299 @@
+package graphql
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+)
+
+// CostBudget is the query cost budget which is reported by the server in response extensions.
+type CostBudget struct {
+	// Maximum is the max budget, e.g. maximumAvailable of Shopify or limit of GitHub.
+	Maximum float64
+	// Available is the estimated budget which is available now,
+	// including the restored budget since the last response and the cost of operations in flight.
+	Available float64
+	// RestoreRate is the budget which is restored per second, e.g. restoreRate of Shopify.
+	RestoreRate float64
+	// ResetAt is the time the budget is reset to the maximum, e.g. resetAt of GitHub.
+	ResetAt time.Time
+	// LastCost is the actual cost of the last operation.
+	LastCost float64
+	// UpdatedAt is the time of the last response which reported the budget, zero if the budget isn't known yet.
+	UpdatedAt time.Time
+}
+
+// CostThrottle delays operations when the remaining query cost budget is below the estimated cost of the operation.
+// The budget is parsed from well-known cost extensions of every response:
+//
+//   - extensions.cost of Shopify: requestedQueryCost, actualQueryCost and throttleStatus
+//     with maximumAvailable, currentlyAvailable and restoreRate.
+//   - extensions.rateLimit with the fields of the GitHub rateLimit object: cost, limit, remaining and resetAt.
+//
+// The estimated cost of an operation is the last reported cost of the same query, or DefaultCost if the query is new.
+// The throttle can be shared between clients of the same server.
+type CostThrottle struct {
+	// DefaultCost is the estimated cost of queries which haven't been sent yet. Default 1.
+	DefaultCost float64
+	// MaxEstimates is the max number of queries whose last cost is kept,
+	// the least recently used query is forgotten first. Default 1000.
+	MaxEstimates int
+
+	mu     sync.Mutex
+	budget CostBudget
+	// remaining is the estimated budget at remainingAt,
+	// the reported budget minus the cost of operations which are sent since the last response.
+	remaining   float64
+	remainingAt time.Time
+	// resetReserved is the cost of the operations which are sent since the budget is reset.
+	resetReserved float64
+	estimates     map[string]*list.Element
+	order         *list.List
+}
+
+const defaultMaxCostEstimates = 1000
+
+// costEstimate is the last reported cost of the query.
+type costEstimate struct {
+	query string
+	cost  float64
+}
+
+// NewCostThrottle creates a throttle of the query cost budget.
+func NewCostThrottle() *CostThrottle {
+	return &CostThrottle{
+		DefaultCost:  1,
+		MaxEstimates: defaultMaxCostEstimates,
+	}
+}
+
+// WithCostThrottle creates an option to delay operations by the query cost budget of the server.
+// The option is ignored if the throttle is nil.
+func WithCostThrottle(throttle *CostThrottle) ClientOption {
+	return func(c *Client) {
+		if throttle == nil {
+			return
+		}
+
+		WithMiddleware(throttle.middleware())(c)
+	}
+}
+
+// Budget returns the current state of the budget. UpdatedAt is zero if the budget isn't reported yet.
+func (ct *CostThrottle) Budget() CostBudget {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	budget := ct.budget
+	budget.Available = ct.available(time.Now())
+
+	return budget
+}
+
+func (ct *CostThrottle) middleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) *Response {
+			for {
+				delay := ct.reserve(req.Query)
+				if delay <= 0 {
+					break
+				}
+
+				if err := sleepWithContext(ctx, delay); err != nil {
+					return &Response{
+						Errors: Errors{newError(ErrRequestError, err)},
+					}
+				}
+			}
+
+			resp := next(ctx, req)
+			if resp != nil {
+				ct.update(req.Query, resp.Extensions)
+			}
+
+			return resp
+		}
+	}
+}
+
+// reserve takes the estimated cost of the query from the budget if it's available,
+// or returns the delay until the budget is enough.
+func (ct *CostThrottle) reserve(query string) time.Duration {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	if ct.budget.UpdatedAt.IsZero() {
+		return 0
+	}
+
+	cost, ok := ct.estimate(query)
+	if !ok {
+		cost = ct.DefaultCost
+	}
+
+	// the query would never be allowed if the cost is greater than the max budget.
+	if ct.budget.Maximum > 0 {
+		cost = math.Min(cost, ct.budget.Maximum)
+	}
+
+	now := time.Now()
+	available := ct.available(now)
+
+	if available >= cost {
+		if ct.isReset(now) {
+			ct.resetReserved += cost
+		} else {
+			ct.remaining = available - cost
+			ct.remainingAt = now
+		}
+
+		return 0
+	}
+
+	if ct.budget.RestoreRate > 0 {
+		return time.Duration((cost - available) / ct.budget.RestoreRate * float64(time.Second))
+	}
+
+	if ct.budget.ResetAt.After(now) {
+		return ct.budget.ResetAt.Sub(now)
+	}
+
+	return 0
+}
+
+// available returns the budget at the time, including the restored budget since the last reservation.
+func (ct *CostThrottle) available(now time.Time) float64 {
+	budget := ct.budget
+	if budget.UpdatedAt.IsZero() {
+		return 0
+	}
+
+	if ct.isReset(now) {
+		return budget.Maximum - ct.resetReserved
+	}
+
+	available := ct.remaining + budget.RestoreRate*now.Sub(ct.remainingAt).Seconds()
+	if budget.Maximum > 0 {
+		available = math.Min(available, budget.Maximum)
+	}
+
+	return available
+}
+
+// isReset reports whether the budget is reset to the maximum since the last response.
+func (ct *CostThrottle) isReset(now time.Time) bool {
+	return !ct.budget.ResetAt.IsZero() && !now.Before(ct.budget.ResetAt)
+}
+
+// costExtensions is the well-known cost extensions of responses.
+type costExtensions struct {
+	Cost *struct {
+		RequestedQueryCost float64  `json:"requestedQueryCost"`
+		ActualQueryCost    *float64 `json:"actualQueryCost"`
+		ThrottleStatus     *struct {
+			MaximumAvailable   float64 `json:"maximumAvailable"`
+			CurrentlyAvailable float64 `json:"currentlyAvailable"`
+			RestoreRate        float64 `json:"restoreRate"`
+		} `json:"throttleStatus"`
+	} `json:"cost"`
+	RateLimit *struct {
+		Cost      float64   `json:"cost"`
+		Limit     float64   `json:"limit"`
+		Remaining float64   `json:"remaining"`
+		ResetAt   time.Time `json:"resetAt"`
+	} `json:"rateLimit"`
+}
+
+// update replaces the budget with the state which is reported in the response extensions.
+func (ct *CostThrottle) update(query string, extensions json.RawMessage) {
+	if len(extensions) == 0 {
+		return
+	}
+
+	var ext costExtensions
+	if err := json.Unmarshal(extensions, &ext); err != nil {
+		return
+	}
+
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	now := time.Now()
+
+	switch {
+	case ext.Cost != nil && ext.Cost.ThrottleStatus != nil:
+		// the actual cost is null if the query is throttled.
+		lastCost := ext.Cost.RequestedQueryCost
+		if ext.Cost.ActualQueryCost != nil {
+			lastCost = *ext.Cost.ActualQueryCost
+		}
+
+		ct.setEstimate(query, ext.Cost.RequestedQueryCost)
+		ct.budget = CostBudget{
+			Maximum:     ext.Cost.ThrottleStatus.MaximumAvailable,
+			Available:   ext.Cost.ThrottleStatus.CurrentlyAvailable,
+			RestoreRate: ext.Cost.ThrottleStatus.RestoreRate,
+			LastCost:    lastCost,
+			UpdatedAt:   now,
+		}
+	case ext.RateLimit != nil:
+		ct.setEstimate(query, ext.RateLimit.Cost)
+		ct.budget = CostBudget{
+			Maximum:   ext.RateLimit.Limit,
+			Available: ext.RateLimit.Remaining,
+			ResetAt:   ext.RateLimit.ResetAt,
+			LastCost:  ext.RateLimit.Cost,
+			UpdatedAt: now,
+		}
+	default:
+		return
+	}
+
+	ct.remaining = ct.budget.Available
+	ct.remainingAt = now
+	ct.resetReserved = 0
+}
+
+// estimate returns the last reported cost of the query. The caller must hold the lock.
+func (ct *CostThrottle) estimate(query string) (float64, bool) {
+	elem, ok := ct.estimates[query]
+	if !ok {
+		return 0, false
+	}
+
+	ct.order.MoveToFront(elem)
+
+	return elem.Value.(*costEstimate).cost, true
+}
+
+// setEstimate stores the cost of the query, and forgets the least recently used query if the limit is reached.
+// The caller must hold the lock.
+func (ct *CostThrottle) setEstimate(query string, cost float64) {
+	if ct.estimates == nil {
+		ct.estimates = map[string]*list.Element{}
+		ct.order = list.New()
+	}
+
+	if elem, ok := ct.estimates[query]; ok {
+		elem.Value.(*costEstimate).cost = cost
+		ct.order.MoveToFront(elem)
+
+		return
+	}
+
+	ct.estimates[query] = ct.order.PushFront(&costEstimate{query: query, cost: cost})
+
+	maxEstimates := ct.MaxEstimates
+	if maxEstimates <= 0 {
+		maxEstimates = defaultMaxCostEstimates
+	}
+
+	for ct.order.Len() > maxEstimates {
+		oldest := ct.order.Back()
+		ct.order.Remove(oldest)
+		delete(ct.estimates, oldest.Value.(*costEstimate).query)
+	}
+}
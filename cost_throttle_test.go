@@ -0,0 +1,280 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+func TestClientOption_WithCostThrottle_shopify(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{
+			"data": {"shop": {"name": "Gopher"}},
+			"extensions": {
+				"cost": {
+					"requestedQueryCost": 10,
+					"actualQueryCost": 8,
+					"throttleStatus": {"maximumAvailable": 1000, "currentlyAvailable": 5, "restoreRate": 50}
+				}
+			}
+		}`)
+	})
+
+	throttle := graphql.NewCostThrottle()
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithCostThrottle(throttle),
+	)
+
+	var q struct {
+		Shop struct {
+			Name string
+		}
+	}
+
+	if err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	budget := throttle.Budget()
+	if budget.Maximum != 1000 || budget.RestoreRate != 50 || budget.LastCost != 8 || budget.UpdatedAt.IsZero() {
+		t.Errorf("got budget: %+v", budget)
+	}
+	if budget.Available < 5 || budget.Available > 10 {
+		t.Errorf("got available: %f, want: 5", budget.Available)
+	}
+
+	// the query waits until the budget is restored to the requested cost, 5 / 50 = 100ms.
+	start := time.Now()
+	if err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("got elapsed: %s, want at least 100ms", elapsed)
+	}
+}
+
+func TestClientOption_WithCostThrottle_rateLimit(t *testing.T) {
+	var requests int32
+	resetAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, fmt.Sprintf(`{
+			"data": {"viewer": {"login": "gopher"}},
+			"extensions": {"rateLimit": {"cost": 1, "limit": 5000, "remaining": 0, "resetAt": %q}}
+		}`, resetAt))
+	})
+
+	throttle := graphql.NewCostThrottle()
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithCostThrottle(throttle),
+	)
+
+	var q struct {
+		Viewer struct {
+			Login string
+		}
+	}
+
+	if err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	budget := throttle.Budget()
+	if budget.Maximum != 5000 || budget.Available != 0 || budget.ResetAt.Format(time.RFC3339) != resetAt {
+		t.Errorf("got budget: %+v", budget)
+	}
+
+	// the operation waits until the budget is reset.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.Query(ctx, &q, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error: %v, want: context deadline exceeded", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("got %d requests, want: 1", got)
+	}
+}
+
+func TestClientOption_WithCostThrottle_reset(t *testing.T) {
+	var requests int32
+	resetAt := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// only the first response reports the budget.
+		if atomic.AddInt32(&requests, 1) > 1 {
+			mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+			return
+		}
+		mustWrite(w, fmt.Sprintf(`{
+			"data": {"viewer": {"login": "gopher"}},
+			"extensions": {"rateLimit": {"cost": 1, "limit": 3, "remaining": 0, "resetAt": %q}}
+		}`, resetAt))
+	})
+
+	throttle := graphql.NewCostThrottle()
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithCostThrottle(throttle),
+	)
+
+	var q struct {
+		Viewer struct {
+			Login string
+		}
+	}
+
+	if err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the budget is reset to the maximum.
+	budget := throttle.Budget()
+	if budget.Available != 3 {
+		t.Errorf("got available: %f, want: 3", budget.Available)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := client.Query(context.Background(), &q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the operations since the reset are taken from the maximum,
+	// and UpdatedAt is still the time of the response which reported the budget.
+	got := throttle.Budget()
+	if got.Available != 1 {
+		t.Errorf("got available: %f, want: 1", got.Available)
+	}
+	if !got.UpdatedAt.Equal(budget.UpdatedAt) {
+		t.Errorf("got updated at: %s, want: %s", got.UpdatedAt, budget.UpdatedAt)
+	}
+}
+
+func TestClientOption_WithCostThrottle_unknownBudget(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}, "extensions": {"tracing": {}}}`)
+	})
+
+	throttle := graphql.NewCostThrottle()
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithCostThrottle(throttle),
+	)
+
+	var q struct {
+		Viewer struct {
+			Login string
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := client.Query(context.Background(), &q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if budget := throttle.Budget(); !budget.UpdatedAt.IsZero() {
+		t.Errorf("got budget: %+v, want: unknown", budget)
+	}
+}
+
+func TestClientOption_WithCostThrottle_maxEstimates(t *testing.T) {
+	var requests int32
+	resetAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		var payload graphql.GraphQLRequestPayload
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			t.Error(err)
+			return
+		}
+		cost, data := 1, `{"user": {"name": "Gopher"}}`
+		if strings.Contains(payload.Query, "viewer") {
+			cost, data = 100, `{"viewer": {"login": "gopher"}}`
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, fmt.Sprintf(`{
+			"data": %s,
+			"extensions": {"rateLimit": {"cost": %d, "limit": 5000, "remaining": 50, "resetAt": %q}}
+		}`, data, cost, resetAt))
+	})
+
+	throttle := graphql.NewCostThrottle()
+	throttle.MaxEstimates = 1
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithCostThrottle(throttle),
+	)
+
+	var viewerQuery struct {
+		Viewer struct {
+			Login string
+		}
+	}
+	var userQuery struct {
+		User struct {
+			Name string
+		}
+	}
+	if err := client.Query(context.Background(), &viewerQuery, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Query(context.Background(), &userQuery, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the cost of the viewer query is forgotten, so the default cost is estimated.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.Query(ctx, &viewerQuery, nil); err != nil {
+		t.Errorf("got error: %v, want: nil", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 3 {
+		t.Errorf("got %d requests, want: 3", got)
+	}
+}
+
+func TestClientOption_WithCostThrottle_nil(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithCostThrottle(nil),
+	)
+
+	var q struct {
+		Viewer struct {
+			Login string
+		}
+	}
+	if err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+}
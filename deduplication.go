@@ -0,0 +1,256 @@
+package graphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// queryDeduplicator shares the result of identical query operations which are in flight at the same time,
+// so only one request is sent to the server.
+type queryDeduplicator struct {
+	mu    sync.Mutex
+	calls map[string]*inflightQuery
+}
+
+// inflightQuery is the query which is being executed.
+// The response is set before the done channel is closed.
+type inflightQuery struct {
+	done chan struct{}
+	resp *Response
+	// the number of callers which wait for the result
+	waiters int
+	// cancel the shared request when every caller has left
+	cancel context.CancelFunc
+	// the callers which wait for the result, attempts of the request are recorded for each of them
+	callers *sharedCallers
+}
+
+// WithQueryDeduplication creates an option to deduplicate identical query operations in flight.
+// If many goroutines execute the same query with the same variables, operation name and headers at the same time,
+// only one request is sent and the result is decoded into the struct of each caller.
+// Mutations are never deduplicated.
+func WithQueryDeduplication(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.deduplicator = nil
+
+		if enabled {
+			c.deduplicator = newQueryDeduplicator()
+		}
+	}
+}
+
+func newQueryDeduplicator() *queryDeduplicator {
+	return &queryDeduplicator{
+		calls: map[string]*inflightQuery{},
+	}
+}
+
+// do executes the query or waits for the result of the identical query in flight.
+// The shared request isn't bound to the context of the first caller, so it isn't canceled while other callers wait.
+// Callers return early if their context is canceled, and the request is canceled when every caller has left.
+// Attempts of the request are recorded in the metrics and the operation span of every waiting caller.
+func (d *queryDeduplicator) do(
+	ctx context.Context,
+	req *Request,
+	execute func(ctx context.Context, req *Request) *Response,
+) *Response {
+	key, err := deduplicationKey(req)
+	if err != nil {
+		return execute(ctx, req)
+	}
+
+	d.mu.Lock()
+
+	call, ok := d.calls[key]
+	if ok {
+		call.waiters++
+	} else {
+		callers := &sharedCallers{}
+		sharedCtx, cancel := context.WithCancel(withSharedCallers(newDetachedContext(ctx), callers))
+		call = &inflightQuery{
+			done:    make(chan struct{}),
+			waiters: 1,
+			cancel:  cancel,
+			callers: callers,
+		}
+		d.calls[key] = call
+
+		go func() {
+			defer cancel()
+
+			call.resp = execute(sharedCtx, req)
+
+			d.mu.Lock()
+			d.remove(key, call)
+			d.mu.Unlock()
+
+			close(call.done)
+		}()
+	}
+
+	leave := call.callers.add(ctx)
+	d.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.resp.clone()
+	case <-ctx.Done():
+		leave()
+
+		d.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			// new callers don't join the canceled request.
+			d.remove(key, call)
+			call.cancel()
+		}
+		d.mu.Unlock()
+
+		return &Response{
+			Errors: Errors{newError(ErrRequestError, ctx.Err())},
+		}
+	}
+}
+
+// remove deletes the call from the calls in flight if it hasn't been replaced.
+func (d *queryDeduplicator) remove(key string, call *inflightQuery) {
+	if d.calls[key] == call {
+		delete(d.calls, key)
+	}
+}
+
+// detachedContext keeps the values of the parent context, but isn't canceled with the parent.
+// The metrics and the span of the operation aren't kept, they belong to the caller of the parent context.
+type detachedContext struct {
+	parent context.Context
+}
+
+// newDetachedContext creates the detached context of the parent.
+// Spans which are started from the context aren't children of the span of the parent.
+func newDetachedContext(parent context.Context) context.Context {
+	return trace.ContextWithSpan(detachedContext{parent}, trace.SpanFromContext(context.Background()))
+}
+
+func (dc detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (dc detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (dc detachedContext) Err() error {
+	return nil
+}
+
+func (dc detachedContext) Value(key any) any {
+	switch key.(type) {
+	case operationMetricsKey, operationSpanKey:
+		return nil
+	}
+
+	return dc.parent.Value(key)
+}
+
+// sharedCallers are the contexts of the callers which wait for a shared request,
+// so attempts of the request are recorded in the metrics and the operation span of every caller.
+type sharedCallers struct {
+	mu      sync.Mutex
+	callers []*sharedCaller
+}
+
+type sharedCaller struct {
+	ctx context.Context //nolint:containedctx
+}
+
+// sharedCallersKey is the context key of the callers of the shared request.
+type sharedCallersKey struct{}
+
+// add adds the context of the caller, and returns the function to remove it when the caller leaves.
+func (sc *sharedCallers) add(ctx context.Context) func() {
+	caller := &sharedCaller{ctx: ctx}
+
+	sc.mu.Lock()
+	sc.callers = append(sc.callers, caller)
+	sc.mu.Unlock()
+
+	return func() {
+		sc.mu.Lock()
+		defer sc.mu.Unlock()
+
+		for i, c := range sc.callers {
+			if c == caller {
+				sc.callers = append(sc.callers[:i], sc.callers[i+1:]...)
+
+				break
+			}
+		}
+	}
+}
+
+// withSharedCallers returns the context of the request which is shared by the callers.
+func withSharedCallers(ctx context.Context, callers *sharedCallers) context.Context {
+	return context.WithValue(ctx, sharedCallersKey{}, callers)
+}
+
+// requestCallers returns the contexts of the callers which wait for the shared request,
+// nil if the request isn't shared.
+func requestCallers(ctx context.Context) []context.Context {
+	sc, ok := ctx.Value(sharedCallersKey{}).(*sharedCallers)
+	if !ok {
+		return nil
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	callers := make([]context.Context, len(sc.callers))
+	for i, caller := range sc.callers {
+		callers[i] = caller.ctx
+	}
+
+	return callers
+}
+
+// deduplicationKey returns the SHA-256 hash of the request payload and headers.
+func deduplicationKey(req *Request) (string, error) {
+	payload, err := json.Marshal(struct {
+		Payload GraphQLRequestPayload `json:"payload"`
+		Header  http.Header           `json:"header,omitempty"`
+	}{
+		Payload: req.payload(),
+		Header:  req.Header,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(payload)
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// clone copies the response for a caller of the shared result,
+// because the result is modified while decoding.
+func (r *Response) clone() *Response {
+	if r == nil {
+		return nil
+	}
+
+	resp := *r
+	resp.Errors = append(Errors(nil), r.Errors...)
+
+	if r.raw != nil {
+		raw := *r.raw
+		resp.raw = &raw
+	}
+
+	return &resp
+}
@@ -0,0 +1,317 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+func TestClientOption_WithQueryDeduplication(t *testing.T) {
+	var requests int32
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		_, _ = io.Copy(io.Discard, req.Body)
+		atomic.AddInt32(&requests, 1)
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}, "errors": [{"message": "partial"}]}`)
+	})
+
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithQueryDeduplication(true),
+	)
+
+	type query struct {
+		User struct {
+			Name string
+		} `graphql:"user(id: $id)"`
+	}
+
+	const callers = 10
+	results := make([]query, callers)
+	errs := make([]error, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = client.Query(context.Background(), &results[i], map[string]any{"id": "1"})
+		}(i)
+	}
+
+	<-started
+	// let other callers join the query in flight.
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("got %d requests, want: 1", got)
+	}
+	for i := 0; i < callers; i++ {
+		var gqlErrs graphql.Errors
+		if !errors.As(errs[i], &gqlErrs) || len(gqlErrs) != 1 || gqlErrs[0].Message != "partial" {
+			t.Errorf("caller %d: got error: %v, want: partial", i, errs[i])
+		}
+		if results[i].User.Name != "Gopher" {
+			t.Errorf("caller %d: got name: %q, want: Gopher", i, results[i].User.Name)
+		}
+	}
+
+	// the query is sent again after the previous one completes.
+	var q query
+	_ = client.Query(context.Background(), &q, map[string]any{"id": "1"})
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("got %d requests, want: 2", got)
+	}
+}
+
+func TestClientOption_WithQueryDeduplication_mutation(t *testing.T) {
+	var requests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		_, _ = io.Copy(io.Discard, req.Body)
+		// wait until both mutations arrive.
+		atomic.AddInt32(&requests, 1)
+		deadline := time.Now().Add(2 * time.Second)
+		for atomic.LoadInt32(&requests) < 2 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"increment": 1}}`)
+	})
+
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithQueryDeduplication(true),
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var m struct {
+				Increment int
+			}
+			if err := client.Mutate(context.Background(), &m, nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("got %d requests, want: 2", got)
+	}
+}
+
+func TestClientOption_WithQueryDeduplication_cancel(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		_, _ = io.Copy(io.Discard, req.Body)
+		close(started)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithQueryDeduplication(true),
+	)
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		var q2 struct {
+			User struct {
+				Name string
+			}
+		}
+		done <- client.Query(context.Background(), &q2, nil)
+	}()
+	<-started
+
+	// the waiting caller returns when its context is canceled, the query in flight continues.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.Query(ctx, &q, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error: %v, want: context deadline exceeded", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClientOption_WithQueryDeduplication_leaderCancel(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	canceled := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		_, _ = io.Copy(io.Discard, req.Body)
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-req.Context().Done():
+			close(canceled)
+
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithQueryDeduplication(true),
+	)
+
+	type query struct {
+		User struct {
+			Name string
+		}
+	}
+
+	// the first caller times out while the follower still waits.
+	leaderCtx, cancelLeader := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelLeader()
+	leaderDone := make(chan error, 1)
+	go func() {
+		var q query
+		leaderDone <- client.Query(leaderCtx, &q, nil)
+	}()
+	<-started
+
+	followerCtx, cancelFollower := context.WithCancel(context.Background())
+	followerDone := make(chan error, 1)
+	var follower query
+	go func() {
+		followerDone <- client.Query(followerCtx, &follower, nil)
+	}()
+
+	if err := <-leaderDone; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error: %v, want: context deadline exceeded", err)
+	}
+
+	// the shared request isn't canceled by the leader.
+	release <- struct{}{}
+	if err := <-followerDone; err != nil {
+		t.Errorf("got error: %v, want: nil", err)
+	}
+	if follower.User.Name != "Gopher" {
+		t.Errorf("got name: %q, want: Gopher", follower.User.Name)
+	}
+
+	// the request is canceled when every caller has left.
+	go func() {
+		var q query
+		followerDone <- client.Query(followerCtx, &q, nil)
+	}()
+	<-started
+	cancelFollower()
+
+	if err := <-followerDone; !errors.Is(err, context.Canceled) {
+		t.Errorf("got error: %v, want: context canceled", err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("the shared request isn't canceled")
+	}
+}
+
+func TestClientOption_WithQueryDeduplication_metrics(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+
+	var mu sync.Mutex
+	records := map[string]graphql.OperationMetrics{}
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithQueryDeduplication(true),
+		graphql.WithMetricsRecorder(metricsRecorderFunc(func(ctx context.Context, metrics graphql.OperationMetrics) {
+			mu.Lock()
+			records[ctx.Value(callerKey{}).(string)] = metrics
+			mu.Unlock()
+		})),
+	)
+
+	type query struct {
+		User struct {
+			Name string
+		}
+	}
+
+	// the first caller times out while the follower still waits.
+	leaderCtx, cancelLeader := context.WithTimeout(context.WithValue(context.Background(), callerKey{}, "leader"), 20*time.Millisecond)
+	defer cancelLeader()
+	leaderDone := make(chan error, 1)
+	go func() {
+		var q query
+		leaderDone <- client.Query(leaderCtx, &q, nil)
+	}()
+	<-started
+
+	followerDone := make(chan error, 1)
+	go func() {
+		var q query
+		followerDone <- client.Query(context.WithValue(context.Background(), callerKey{}, "follower"), &q, nil)
+	}()
+
+	if err := <-leaderDone; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error: %v, want: context deadline exceeded", err)
+	}
+	close(release)
+	if err := <-followerDone; err != nil {
+		t.Fatal(err)
+	}
+
+	// the attempt is recorded for the caller which still waits.
+	mu.Lock()
+	defer mu.Unlock()
+	if got := records["leader"]; got.Attempts != 0 {
+		t.Errorf("got leader metrics: %+v, want: 0 attempts", got)
+	}
+	if got := records["follower"]; got.Attempts != 1 || got.StatusCode != http.StatusOK {
+		t.Errorf("got follower metrics: %+v, want: 1 attempt with status 200", got)
+	}
+}
+
+type callerKey struct{}
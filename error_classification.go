@@ -0,0 +1,196 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrorClass is the category of the error, which is recognized from the error code of well-known servers,
+// e.g. Apollo, Hasura and graphql-java, or the HTTP status of the response.
+type ErrorClass string
+
+const (
+	// ErrorClassUnknown is the class of errors which aren't recognized.
+	ErrorClassUnknown ErrorClass = ""
+	// ErrorClassUnauthenticated is the class of errors of missing or invalid credentials.
+	ErrorClassUnauthenticated ErrorClass = "unauthenticated"
+	// ErrorClassForbidden is the class of errors of insufficient permissions.
+	ErrorClassForbidden ErrorClass = "forbidden"
+	// ErrorClassValidation is the class of errors of invalid queries or variables.
+	ErrorClassValidation ErrorClass = "validation"
+	// ErrorClassRateLimited is the class of errors of exceeded rate limits or query cost budgets.
+	ErrorClassRateLimited ErrorClass = "rate_limited"
+	// ErrorClassTransient is the class of errors which may succeed if the operation is retried later.
+	ErrorClassTransient ErrorClass = "transient"
+	// ErrorClassInternal is the class of unexpected errors of the server.
+	ErrorClassInternal ErrorClass = "internal"
+)
+
+// errorCodeClasses maps normalized error codes of well-known servers to classes.
+// Codes are upper-cased, and dashes are replaced with underscores, e.g. access-denied of Hasura is ACCESS_DENIED.
+var errorCodeClasses = map[string]ErrorClass{
+	// Apollo Server
+	"UNAUTHENTICATED":               ErrorClassUnauthenticated,
+	"FORBIDDEN":                     ErrorClassForbidden,
+	"GRAPHQL_PARSE_FAILED":          ErrorClassValidation,
+	"GRAPHQL_VALIDATION_FAILED":     ErrorClassValidation,
+	"BAD_USER_INPUT":                ErrorClassValidation,
+	"BAD_REQUEST":                   ErrorClassValidation,
+	"OPERATION_RESOLUTION_FAILURE":  ErrorClassValidation,
+	"PERSISTED_QUERY_NOT_SUPPORTED": ErrorClassValidation,
+	"INTERNAL_SERVER_ERROR":         ErrorClassInternal,
+	// Hasura
+	"INVALID_JWT":             ErrorClassUnauthenticated,
+	"INVALID_HEADERS":         ErrorClassUnauthenticated,
+	"JWT_INVALID_CLAIMS":      ErrorClassUnauthenticated,
+	"JWT_MISSING_ROLE_CLAIMS": ErrorClassUnauthenticated,
+	"ACCESS_DENIED":           ErrorClassForbidden,
+	"PERMISSION_DENIED":       ErrorClassForbidden,
+	"PERMISSION_ERROR":        ErrorClassForbidden,
+	"VALIDATION_FAILED":       ErrorClassValidation,
+	"PARSE_FAILED":            ErrorClassValidation,
+	"INVALID_PARAMS":          ErrorClassValidation,
+	"NOT_SUPPORTED":           ErrorClassValidation,
+	"CONSTRAINT_VIOLATION":    ErrorClassValidation,
+	"DATA_EXCEPTION":          ErrorClassValidation,
+	"RATE_LIMIT_EXCEEDED":     ErrorClassRateLimited,
+	"POSTGRES_ERROR":          ErrorClassInternal,
+	"UNEXPECTED":              ErrorClassInternal,
+	// graphql-java classifications, Spring for GraphQL and Netflix DGS error types
+	"VALIDATIONERROR":       ErrorClassValidation,
+	"INVALIDSYNTAX":         ErrorClassValidation,
+	"OPERATIONNOTSUPPORTED": ErrorClassValidation,
+	"DATAFETCHINGEXCEPTION": ErrorClassInternal,
+	"EXECUTIONABORTED":      ErrorClassTransient,
+	"UNAUTHORIZED":          ErrorClassUnauthenticated,
+	"INTERNAL_ERROR":        ErrorClassInternal,
+	"INTERNAL":              ErrorClassInternal,
+	"UNAVAILABLE":           ErrorClassTransient,
+	// common codes of rate limits and availability
+	"RATE_LIMITED":        ErrorClassRateLimited,
+	"TOO_MANY_REQUESTS":   ErrorClassRateLimited,
+	"THROTTLED":           ErrorClassRateLimited,
+	"MAX_COST_EXCEEDED":   ErrorClassRateLimited,
+	"SERVICE_UNAVAILABLE": ErrorClassTransient,
+	"TIMEOUT":             ErrorClassTransient,
+	// client errors
+	strings.ToUpper(ErrCircuitOpen): ErrorClassTransient,
+}
+
+// Code returns the code of the error in the extensions, e.g. UNAUTHENTICATED of Apollo or access-denied of Hasura.
+// Errors of the client have the codes of the client, e.g. ErrRequestError. It returns empty if the error doesn't have a code.
+func (e Error) Code() string {
+	code, _ := e.Extensions["code"].(string)
+
+	return code
+}
+
+// Class returns the class of the error which is recognized from the error code of the server or the client.
+// The classification of graphql-java and the errorType of Netflix DGS are used if the error doesn't have a code.
+// Errors of HTTP responses without a known code are classified by the status code.
+func (e Error) Class() ErrorClass {
+	for _, key := range []string{"code", "classification", "errorType"} {
+		code, ok := e.Extensions[key].(string)
+		if !ok || code == "" {
+			continue
+		}
+
+		if class, ok := errorCodeClasses[strings.ToUpper(strings.ReplaceAll(code, "-", "_"))]; ok {
+			return class
+		}
+	}
+
+	var networkErr NetworkError
+	if errors.As(e.err, &networkErr) {
+		return classifyHTTPStatus(networkErr.StatusCode())
+	}
+
+	if e.err != nil && e.Code() == ErrRequestError {
+		// the request failed without a response, e.g. the connection is refused.
+		if errors.Is(e.err, context.Canceled) {
+			return ErrorClassUnknown
+		}
+
+		return ErrorClassTransient
+	}
+
+	return ErrorClassUnknown
+}
+
+// HasCode reports whether any error has the code.
+func (e Errors) HasCode(code string) bool {
+	for _, err := range e {
+		if err.Code() == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasClass reports whether any error has the class.
+func (e Errors) HasClass(class ErrorClass) bool {
+	for _, err := range e {
+		if err.Class() == class {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DecodeExtensions decodes the extensions of the error into the typed value.
+func DecodeExtensions[T any](err Error) (T, error) {
+	var result T
+
+	if len(err.Extensions) == 0 {
+		return result, nil
+	}
+
+	data, e := json.Marshal(err.Extensions)
+	if e != nil {
+		return result, e
+	}
+
+	if e := json.Unmarshal(data, &result); e != nil {
+		return result, e
+	}
+
+	return result, nil
+}
+
+// As supports the *NetworkError target of errors.As.
+func (e NetworkError) As(target any) bool {
+	if t, ok := target.(**NetworkError); ok {
+		networkErr := e
+		*t = &networkErr
+
+		return true
+	}
+
+	return false
+}
+
+func classifyHTTPStatus(status int) ErrorClass {
+	switch status {
+	case http.StatusUnauthorized:
+		return ErrorClassUnauthenticated
+	case http.StatusForbidden:
+		return ErrorClassForbidden
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return ErrorClassValidation
+	case http.StatusTooManyRequests:
+		return ErrorClassRateLimited
+	case http.StatusRequestTimeout, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ErrorClassTransient
+	default:
+		if status >= 500 {
+			return ErrorClassInternal
+		}
+
+		return ErrorClassUnknown
+	}
+}
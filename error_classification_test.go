@@ -0,0 +1,191 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+func TestErrors_classification(t *testing.T) {
+	testCases := []struct {
+		name     string
+		status   int
+		response string
+		code     string
+		class    graphql.ErrorClass
+	}{
+		{
+			name:     "apollo unauthenticated",
+			status:   http.StatusOK,
+			response: `{"errors": [{"message": "not logged in", "extensions": {"code": "UNAUTHENTICATED"}}]}`,
+			code:     "UNAUTHENTICATED",
+			class:    graphql.ErrorClassUnauthenticated,
+		},
+		{
+			name:     "apollo validation",
+			status:   http.StatusOK,
+			response: `{"errors": [{"message": "unknown field", "extensions": {"code": "GRAPHQL_VALIDATION_FAILED"}}]}`,
+			code:     "GRAPHQL_VALIDATION_FAILED",
+			class:    graphql.ErrorClassValidation,
+		},
+		{
+			name:     "hasura access denied",
+			status:   http.StatusOK,
+			response: `{"errors": [{"message": "denied", "extensions": {"code": "access-denied", "path": "$"}}]}`,
+			code:     "access-denied",
+			class:    graphql.ErrorClassForbidden,
+		},
+		{
+			name:     "hasura invalid jwt",
+			status:   http.StatusOK,
+			response: `{"errors": [{"message": "expired", "extensions": {"code": "invalid-jwt", "path": "$"}}]}`,
+			code:     "invalid-jwt",
+			class:    graphql.ErrorClassUnauthenticated,
+		},
+		{
+			name:     "graphql-java classification",
+			status:   http.StatusOK,
+			response: `{"errors": [{"message": "failed", "extensions": {"classification": "DataFetchingException"}}]}`,
+			class:    graphql.ErrorClassInternal,
+		},
+		{
+			name:     "shopify throttled",
+			status:   http.StatusOK,
+			response: `{"errors": [{"message": "Throttled", "extensions": {"code": "THROTTLED"}}]}`,
+			code:     "THROTTLED",
+			class:    graphql.ErrorClassRateLimited,
+		},
+		{
+			name:     "unknown code",
+			status:   http.StatusOK,
+			response: `{"errors": [{"message": "oops", "extensions": {"code": "CUSTOM"}}]}`,
+			code:     "CUSTOM",
+			class:    graphql.ErrorClassUnknown,
+		},
+		{
+			name:     "http unavailable",
+			status:   http.StatusServiceUnavailable,
+			response: "unavailable",
+			code:     graphql.ErrRequestError,
+			class:    graphql.ErrorClassTransient,
+		},
+		{
+			name:     "http unauthorized",
+			status:   http.StatusUnauthorized,
+			response: "unauthorized",
+			code:     graphql.ErrRequestError,
+			class:    graphql.ErrorClassUnauthenticated,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.status)
+				mustWrite(w, tc.response)
+			})
+
+			client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+			var q struct {
+				User struct {
+					Name string
+				}
+			}
+			err := client.Query(context.Background(), &q, nil)
+
+			var errs graphql.Errors
+			if !errors.As(err, &errs) || len(errs) != 1 {
+				t.Fatalf("got error: %v, want 1 error", err)
+			}
+			if code := errs[0].Code(); code != tc.code {
+				t.Errorf("got code: %q, want: %q", code, tc.code)
+			}
+			if tc.code != "" && !errs.HasCode(tc.code) {
+				t.Errorf("got HasCode: false, want: true")
+			}
+			if class := errs[0].Class(); class != tc.class {
+				t.Errorf("got class: %q, want: %q", class, tc.class)
+			}
+			if tc.class != graphql.ErrorClassUnknown && !errs.HasClass(tc.class) {
+				t.Errorf("got HasClass: false, want: true")
+			}
+		})
+	}
+}
+
+func TestErrors_networkError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	})
+
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	err := client.Query(context.Background(), &q, nil)
+
+	var networkErr graphql.NetworkError
+	if !errors.As(err, &networkErr) || networkErr.StatusCode() != http.StatusBadGateway {
+		t.Errorf("got error: %v, want: NetworkError", err)
+	}
+
+	var networkErrPtr *graphql.NetworkError
+	if !errors.As(err, &networkErrPtr) || networkErrPtr.StatusCode() != http.StatusBadGateway {
+		t.Errorf("got error: %v, want: *NetworkError", err)
+	}
+}
+
+func TestDecodeExtensions(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"errors": [{
+			"message": "too many requests",
+			"extensions": {"code": "RATE_LIMITED", "retryAfter": 30, "limits": {"cost": 1000}}
+		}]}`)
+	})
+
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	err := client.Query(context.Background(), &q, nil)
+
+	var errs graphql.Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("got error: %v, want: graphql errors", err)
+	}
+
+	type rateLimitExtensions struct {
+		Code       string `json:"code"`
+		RetryAfter int    `json:"retryAfter"`
+		Limits     struct {
+			Cost int `json:"cost"`
+		} `json:"limits"`
+	}
+
+	ext, err := graphql.DecodeExtensions[rateLimitExtensions](errs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ext.Code != "RATE_LIMITED" || ext.RetryAfter != 30 || ext.Limits.Cost != 1000 {
+		t.Errorf("got extensions: %+v", ext)
+	}
+
+	if _, err := graphql.DecodeExtensions[int](errs[0]); err == nil {
+		t.Error("got error: nil, want: json error")
+	}
+}
@@ -0,0 +1,65 @@
+package graphql
+
+import (
+	"bytes"
+)
+
+// ErrorPolicy decides how GraphQL errors of the response are reported with the partial data,
+// like the errorPolicy of Apollo Client. It's used as a per-operation Option, or the default of the client with WithErrorPolicy.
+// The policy applies to operations which decode the data into a struct, e.g. Query, Mutate, Exec and Batch.
+type ErrorPolicy string
+
+const (
+	// ErrorPolicyNone fails the operation if the response has any error, the data isn't decoded so the struct is left untouched.
+	ErrorPolicyNone ErrorPolicy = "none"
+	// ErrorPolicyIgnore decodes the data and ignores GraphQL errors of the response, which are only exposed through BindErrors.
+	// Errors are still returned if the response doesn't have data, e.g. the request failed, or the data can't be decoded.
+	ErrorPolicyIgnore ErrorPolicy = "ignore"
+	// ErrorPolicyAll decodes the partial data and returns the errors too. It's the default policy.
+	ErrorPolicyAll ErrorPolicy = "all"
+)
+
+// Type implements the Option interface, so the policy can be set per operation.
+func (ep ErrorPolicy) Type() OptionType {
+	return "error_policy"
+}
+
+// WithErrorPolicy creates an option to set the default error policy of operations.
+func WithErrorPolicy(policy ErrorPolicy) ClientOption {
+	return func(c *Client) {
+		c.errorPolicy = policy
+	}
+}
+
+// bind the pointer to return errors of the response.
+type bindErrorsOption struct {
+	value *Errors
+}
+
+func (ono bindErrorsOption) Type() OptionType {
+	return "bind_errors"
+}
+
+// BindErrors binds the errors of the response to the pointer, whichever error policy is used.
+// The value is set to nil if the operation succeeds without errors.
+func BindErrors(value *Errors) Option {
+	return bindErrorsOption{value: value}
+}
+
+// getErrorPolicy returns the error policy of the operation, or the default of the client.
+func (c *Client) getErrorPolicy(options *constructOptionsOutput) ErrorPolicy {
+	if options != nil && options.errorPolicy != "" {
+		return options.errorPolicy
+	}
+
+	if c.errorPolicy != "" {
+		return c.errorPolicy
+	}
+
+	return ErrorPolicyAll
+}
+
+// hasData reports whether the response has non-null data, including the data which is decoded from the response stream.
+func (r *rawGraphQLResult) hasData() bool {
+	return r.streamedData || (len(r.Data) > 0 && !bytes.Equal(r.Data, []byte("null")))
+}
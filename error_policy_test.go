@@ -0,0 +1,273 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"testing/iotest"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+func TestErrorPolicy(t *testing.T) {
+	const partialResponse = `{
+		"data": {"user": {"name": "Gopher", "email": null}},
+		"errors": [{"message": "forbidden", "path": ["user", "email"]}]
+	}`
+
+	testCases := []struct {
+		name          string
+		clientOptions []graphql.ClientOption
+		options       []graphql.Option
+		response      string
+		wantErr       bool
+		wantName      string
+		wantBound     int
+	}{
+		{
+			name:      "default",
+			response:  partialResponse,
+			wantErr:   true,
+			wantName:  "Gopher",
+			wantBound: 1,
+		},
+		{
+			name:      "all",
+			options:   []graphql.Option{graphql.ErrorPolicyAll},
+			response:  partialResponse,
+			wantErr:   true,
+			wantName:  "Gopher",
+			wantBound: 1,
+		},
+		{
+			name:      "none",
+			options:   []graphql.Option{graphql.ErrorPolicyNone},
+			response:  partialResponse,
+			wantErr:   true,
+			wantBound: 1,
+		},
+		{
+			name:     "none without errors",
+			options:  []graphql.Option{graphql.ErrorPolicyNone},
+			response: `{"data": {"user": {"name": "Gopher", "email": "gopher@example.com"}}}`,
+			wantName: "Gopher",
+		},
+		{
+			name:      "ignore",
+			options:   []graphql.Option{graphql.ErrorPolicyIgnore},
+			response:  partialResponse,
+			wantName:  "Gopher",
+			wantBound: 1,
+		},
+		{
+			name:      "ignore without data",
+			options:   []graphql.Option{graphql.ErrorPolicyIgnore},
+			response:  `{"data": null, "errors": [{"message": "unauthorized"}]}`,
+			wantErr:   true,
+			wantBound: 1,
+		},
+		{
+			name:          "client default",
+			clientOptions: []graphql.ClientOption{graphql.WithErrorPolicy(graphql.ErrorPolicyIgnore)},
+			response:      partialResponse,
+			wantName:      "Gopher",
+			wantBound:     1,
+		},
+		{
+			name:          "override the client default",
+			clientOptions: []graphql.ClientOption{graphql.WithErrorPolicy(graphql.ErrorPolicyIgnore)},
+			options:       []graphql.Option{graphql.ErrorPolicyNone},
+			response:      partialResponse,
+			wantErr:       true,
+			wantBound:     1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				mustWrite(w, tc.response)
+			})
+
+			client := graphql.NewClient(
+				"/graphql",
+				&http.Client{Transport: localRoundTripper{handler: mux}},
+				tc.clientOptions...,
+			)
+
+			var q struct {
+				User struct {
+					Name  string
+					Email *string
+				}
+			}
+			bound := graphql.Errors{{Message: "stale"}}
+			err := client.Query(context.Background(), &q, nil, append(tc.options, graphql.BindErrors(&bound))...)
+
+			if (err != nil) != tc.wantErr {
+				t.Errorf("got error: %v, want error: %t", err, tc.wantErr)
+			}
+			if q.User.Name != tc.wantName {
+				t.Errorf("got name: %q, want: %q", q.User.Name, tc.wantName)
+			}
+			if len(bound) != tc.wantBound {
+				t.Errorf("got %d bound errors, want: %d", len(bound), tc.wantBound)
+			}
+		})
+	}
+}
+
+func TestErrorPolicy_ignoreDecodeError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": 1}}, "errors": [{"message": "partial"}]}`)
+	})
+
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	var bound graphql.Errors
+	err := client.Query(context.Background(), &q, nil, graphql.ErrorPolicyIgnore, graphql.BindErrors(&bound))
+
+	var errs graphql.Errors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Code() != graphql.ErrGraphQLDecode {
+		t.Fatalf("got error: %v, want: decode error", err)
+	}
+	if len(bound) != 2 {
+		t.Errorf("got %d bound errors, want: 2", len(bound))
+	}
+}
+
+// oneByteRoundTripper reads the response body one byte at a time, so values are split between reads.
+type oneByteRoundTripper struct {
+	handler http.Handler
+}
+
+func (rt oneByteRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := localRoundTripper(rt).RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(iotest.OneByteReader(resp.Body))
+
+	return resp, nil
+}
+
+func TestErrorPolicy_streaming(t *testing.T) {
+	testCases := []struct {
+		name      string
+		transport func(handler http.Handler) http.RoundTripper
+		response  string
+		wantErr   bool
+		wantName  string
+	}{
+		{
+			name:     "partial data",
+			response: `{"data": {"user": {"name": "Gopher"}}, "errors": [{"message": "forbidden", "path": ["user", "email"]}]}`,
+			wantName: "Gopher",
+		},
+		{
+			name: "partial data read byte by byte",
+			transport: func(handler http.Handler) http.RoundTripper {
+				return oneByteRoundTripper{handler: handler}
+			},
+			response: `{"data"  :  {"user": {"name": "Gopher"}}, "errors": [{"message": "forbidden", "path": ["user", "email"]}]}`,
+			wantName: "Gopher",
+		},
+		{
+			name:     "errors before the data",
+			response: `{"errors": [{"message": "forbidden", "path": ["user", "email"]}], "data": {"user": {"name": "Gopher"}}}`,
+			wantName: "Gopher",
+		},
+		{
+			name:     "null data",
+			response: `{"data": null, "errors": [{"message": "unauthorized"}]}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				mustWrite(w, tc.response)
+			})
+
+			var transport http.RoundTripper = localRoundTripper{handler: mux}
+			if tc.transport != nil {
+				transport = tc.transport(mux)
+			}
+			client := graphql.NewClient(
+				"/graphql",
+				&http.Client{Transport: transport},
+				graphql.WithStreamingDecode(true),
+				graphql.WithErrorPolicy(graphql.ErrorPolicyIgnore),
+			)
+
+			var q struct {
+				User struct {
+					Name  string
+					Email *string
+				}
+			}
+			var bound graphql.Errors
+			err := client.Query(context.Background(), &q, nil, graphql.BindErrors(&bound))
+
+			if (err != nil) != tc.wantErr {
+				t.Errorf("got error: %v, want error: %t", err, tc.wantErr)
+			}
+			if q.User.Name != tc.wantName {
+				t.Errorf("got name: %q, want: %q", q.User.Name, tc.wantName)
+			}
+			if len(bound) != 1 {
+				t.Errorf("got %d bound errors, want: 1", len(bound))
+			}
+		})
+	}
+}
+
+func TestErrorPolicy_streamQuery(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{
+			"data": {"users": {"nodes": [{"name": "Gopher"}, null]}},
+			"errors": [{"message": "forbidden", "path": ["users", "nodes", 1]}]
+		}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	type user struct {
+		Name string
+	}
+	var q struct {
+		Users struct {
+			Nodes []*user
+		}
+	}
+	var names []string
+	err := graphql.StreamQuery(context.Background(), client, &q, "users.nodes", nil, func(u *user) error {
+		if u != nil {
+			names = append(names, u.Name)
+		}
+
+		return nil
+	}, graphql.ErrorPolicyIgnore)
+	if err != nil {
+		t.Errorf("got error: %v, want: nil", err)
+	}
+	if len(names) != 1 || names[0] != "Gopher" {
+		t.Errorf("got names: %v, want: [Gopher]", names)
+	}
+}
@@ -0,0 +1,88 @@
+package graphql
+
+import (
+	"reflect"
+
+	"github.com/hasura/go-graphql-client/pkg/jsonutil"
+)
+
+// Field returns the pointer to the struct field or slice element of the decoded value which the error path points to,
+// or nil if the path isn't resolved. If a parent of the path is null, the pointer to the deepest non-null parent is returned.
+func (e Error) Field() any {
+	if len(e.fields) == 0 {
+		return nil
+	}
+
+	return e.fields[len(e.fields)-1].Addr().Interface()
+}
+
+// ForField returns errors of which the path points to the field or its descendants.
+// The field is a pointer to a struct field or a slice element of the decoded value, e.g. errs.ForField(&q.User.Orders).
+func (e Errors) ForField(field any) Errors {
+	target := reflect.ValueOf(field)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return nil
+	}
+
+	var errs Errors
+
+	for _, err := range e {
+		for _, f := range err.fields {
+			if isSameField(f, target) {
+				errs = append(errs, err)
+
+				break
+			}
+		}
+	}
+
+	return errs
+}
+
+// IsNullByError reports whether the field is null because of an error, rather than genuinely null in the response.
+// The field is a pointer to a struct field or a slice element of the decoded value.
+// It's true if the value is zero and an error path points to the field or its descendants,
+// or the field is nested in a null parent which an error path points to.
+// Use pointer types for nullable fields to distinguish null values from zero values.
+func (e Errors) IsNullByError(field any) bool {
+	target := reflect.ValueOf(field)
+	if target.Kind() != reflect.Ptr || target.IsNil() || !target.Elem().IsZero() {
+		return false
+	}
+
+	for _, err := range e {
+		for i, f := range err.fields {
+			if isSameField(f, target) {
+				return true
+			}
+
+			// the error nulls the parent, e.g. a non-null field of the parent fails.
+			if i == len(err.fields)-1 && f.IsZero() && isNestedField(f, target) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// resolveFields resolves the paths of errors to fields of the decoded value.
+func (e Errors) resolveFields(v any) {
+	for i := range e {
+		if len(e[i].Path) > 0 {
+			e[i].fields = jsonutil.ResolvePath(v, e[i].Path)
+		}
+	}
+}
+
+func isSameField(f reflect.Value, target reflect.Value) bool {
+	return f.Addr().Pointer() == target.Pointer() && f.Type() == target.Type().Elem()
+}
+
+// isNestedField reports whether the target is in the memory of the parent value, e.g. a field of a nested struct.
+func isNestedField(parent reflect.Value, target reflect.Value) bool {
+	start := parent.Addr().Pointer()
+	p := target.Pointer()
+
+	return p >= start && p+target.Type().Elem().Size() <= start+parent.Type().Size()
+}
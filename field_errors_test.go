@@ -0,0 +1,159 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+func TestErrors_ForField(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{
+			"data": {
+				"user": {
+					"name": "Gopher",
+					"email": null,
+					"nickname": null,
+					"profile": null,
+					"orders": [{"id": "1", "total": 10}, {"id": "2", "total": null}],
+					"item": {"__typename": "Book", "title": null}
+				}
+			},
+			"errors": [
+				{"message": "forbidden", "path": ["user", "email"]},
+				{"message": "price service is unavailable", "path": ["user", "orders", 1, "total"]},
+				{"message": "profile is unavailable", "path": ["user", "profile", "bio"]},
+				{"message": "title is unavailable", "path": ["user", "item", "title"]},
+				{"message": "unknown"}
+			]
+		}`)
+	})
+
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		User struct {
+			Name     string
+			Email    *string
+			Nickname *string
+			Profile  struct {
+				Bio string
+			}
+			Orders []struct {
+				ID    string
+				Total *float64
+			}
+			Item struct {
+				Typename string `graphql:"__typename"`
+				Book     struct {
+					Title *string
+				} `graphql:"... on Book"`
+			}
+		}
+	}
+
+	err := client.Query(context.Background(), &q, nil)
+
+	var errs graphql.Errors
+	if !errors.As(err, &errs) || len(errs) != 5 {
+		t.Fatalf("got error: %v, want 5 errors", err)
+	}
+
+	if field, ok := errs[0].Field().(**string); !ok || field != &q.User.Email {
+		t.Errorf("got field: %v, want: &q.User.Email", errs[0].Field())
+	}
+	if errs[4].Field() != nil {
+		t.Errorf("got field: %v, want: nil", errs[4].Field())
+	}
+
+	testCases := []struct {
+		name  string
+		field any
+		want  []string
+	}{
+		{"user", &q.User, []string{"forbidden", "price service is unavailable", "profile is unavailable", "title is unavailable"}},
+		{"email", &q.User.Email, []string{"forbidden"}},
+		{"orders", &q.User.Orders, []string{"price service is unavailable"}},
+		{"order", &q.User.Orders[1], []string{"price service is unavailable"}},
+		{"first order", &q.User.Orders[0], nil},
+		{"fragment", &q.User.Item.Book.Title, []string{"title is unavailable"}},
+		{"name", &q.User.Name, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := errs.ForField(tc.field)
+			if len(got) != len(tc.want) {
+				t.Fatalf("got errors: %v, want: %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i].Message != tc.want[i] {
+					t.Errorf("got error: %s, want: %s", got[i].Message, tc.want[i])
+				}
+			}
+		})
+	}
+
+	nullCases := []struct {
+		name  string
+		field any
+		want  bool
+	}{
+		{"null by error", &q.User.Email, true},
+		{"genuinely null", &q.User.Nickname, false},
+		{"not null", &q.User.Name, false},
+		{"null parent", &q.User.Profile.Bio, true},
+		{"null field of list element", &q.User.Orders[1].Total, true},
+		{"null field of fragment", &q.User.Item.Book.Title, true},
+	}
+
+	for _, tc := range nullCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := errs.IsNullByError(tc.field); got != tc.want {
+				t.Errorf("got: %t, want: %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestErrors_ForField_streaming(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{
+			"data": {"user": {"name": "Gopher", "email": null}},
+			"errors": [{"message": "forbidden", "path": ["user", "email"]}]
+		}`)
+	})
+
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithStreamingDecode(true))
+
+	var q struct {
+		User struct {
+			Name  string
+			Email *string
+		}
+	}
+
+	err := client.Query(context.Background(), &q, nil)
+
+	var errs graphql.Errors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("got error: %v, want 1 error", err)
+	}
+	if q.User.Name != "Gopher" {
+		t.Errorf("got name: %q, want: %q", q.User.Name, "Gopher")
+	}
+	if field, ok := errs[0].Field().(**string); !ok || field != &q.User.Email {
+		t.Errorf("got field: %v, want: &q.User.Email", errs[0].Field())
+	}
+	if !errs.IsNullByError(&q.User.Email) {
+		t.Error("got: false, want: email null by error")
+	}
+}
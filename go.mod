@@ -5,4 +5,15 @@ go 1.20
 require (
 	github.com/coder/websocket v1.8.13
 	github.com/google/uuid v1.6.0
+	github.com/klauspost/compress v1.17.9
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/sdk v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.1 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/otel/metric v1.24.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
 )
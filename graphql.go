@@ -4,15 +4,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
-	"math"
 	"net/http"
-	"strconv"
+	"net/url"
+	"reflect"
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/hasura/go-graphql-client/pkg/jsonutil"
 )
 
@@ -31,16 +31,43 @@ type Client struct {
 	httpClient      Doer
 	requestModifier RequestModifier
 	debug           bool
-	// max number of retry times, defaults to 0 for no retry
-	maxRetries int
-	// base delay between retries, default 1 second
-	retryBaseDelay time.Duration
-	// retry exponential rate, default 2.0
-	retryExponentialRate float64
-	// only retry on the following http statuses
-	retryHttpStatus []int
-	// set the callback to retry on specific graphql errors
-	retryOnGraphQLError func(errs Errors) bool
+	// decide whether and when failed requests are retried, no retry if nil
+	retryPolicy RetryPolicy
+	// attach the unique key header to mutations, so they can be retried
+	idempotencyKeyHeader string
+	// send the hash of the query first, following the automatic persisted queries protocol
+	persistedQueries bool
+	// send query operations with the GET method
+	useHTTPGet bool
+	// max length of the GET request URL, fallback to POST if the URL is longer
+	maxGetURLLength int
+	// collect query operations within the window and send them in a batch
+	batchWindow  time.Duration
+	maxBatchSize int
+	batcher      *queryBatcher
+	// intercept operations in order
+	middlewares []Middleware
+	// OpenTelemetry settings
+	tracing tracing
+	// record metrics of every operation
+	metricsRecorder MetricsRecorder
+	// answer queries from normalized entities
+	normalizedCache *NormalizedCache
+	// share results of identical queries in flight
+	deduplicator *queryDeduplicator
+	// fail fast while the endpoint degrades
+	circuitBreaker *CircuitBreaker
+	// limit request attempts, shared by all operations
+	rateLimiter *RateLimiter
+	// compress JSON request bodies with gzip if they are at least the min size
+	requestCompression        bool
+	requestCompressionMinSize int
+	// the Accept-Encoding header of requests, responses are decompressed by the Content-Encoding header
+	acceptEncoding string
+	// decode the data straight from the response body
+	streamingDecode bool
+	// the default policy of reporting errors with partial data
+	errorPolicy ErrorPolicy
 }
 
 // NewClient creates a GraphQL client targeting the specified GraphQL server URL.
@@ -51,23 +78,20 @@ func NewClient(url string, httpClient Doer, options ...ClientOption) *Client {
 	}
 
 	c := &Client{
-		url:                  url,
-		httpClient:           httpClient,
-		requestModifier:      nil,
-		retryBaseDelay:       time.Second,
-		retryExponentialRate: 2,
-		retryHttpStatus: []int{
-			http.StatusTooManyRequests,
-			http.StatusBadGateway,
-			http.StatusServiceUnavailable,
-			http.StatusGatewayTimeout,
-		},
+		url:             url,
+		httpClient:      httpClient,
+		requestModifier: nil,
+		maxGetURLLength: defaultMaxGetURLLength,
 	}
 
 	for _, opt := range options {
 		opt(c)
 	}
 
+	if c.batchWindow > 0 {
+		c.batcher = newQueryBatcher(c)
+	}
+
 	return c
 }
 
@@ -194,12 +218,60 @@ func (c *Client) buildQueryAndOptions(
 	return query, optionOutput, nil
 }
 
-// execute the http request with backoff retries.
-func (c *Client) doHttpRequest(ctx context.Context, body io.ReadSeeker) *rawGraphQLResult {
-	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+// graphqlHTTPRequest holds the HTTP information of an encoded GraphQL request.
+type graphqlHTTPRequest struct {
+	method      string
+	url         string
+	contentType string
+	header      http.Header
+	body        io.ReadSeeker
+	// the request body is an array of operations
+	batch bool
+	op    operationType
+}
+
+// execute the http request with retries of the retry policy.
+// Every attempt is traced with a child span of the operation.
+func (c *Client) doHttpRequest(ctx context.Context, input graphqlHTTPRequest) *rawGraphQLResult {
+	start := time.Now()
+	canRetry := c.canRetry(input)
+
+	body, contentEncoding, err := c.compressRequestBody(input)
+	if err != nil {
+		return &rawGraphQLResult{
+			Errors: Errors{newError(ErrGraphQLEncode, err)},
+		}
+	}
+
+	var result *rawGraphQLResult
+
+	for attempt := 1; ; attempt++ {
+		if err := c.waitRateLimit(ctx); err != nil {
+			if result == nil {
+				result = &rawGraphQLResult{}
+			}
+
+			result.Errors = append(result.Errors, newError(ErrRequestError, err))
+
+			return result
+		}
+
+		if err := c.allowAttempt(); err != nil {
+			if result == nil {
+				result = &rawGraphQLResult{}
+			}
+
+			result.Errors = append(result.Errors, newError(ErrCircuitOpen, err))
+
+			return result
+		}
+
 		_, _ = body.Seek(0, io.SeekStart)
 
-		request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, body)
+		setAttempts(ctx, attempt)
+		attemptCtx, span := c.tracing.startAttempt(ctx, input.method, input.url, attempt)
+
+		request, err := http.NewRequestWithContext(attemptCtx, input.method, input.url, body)
 		if err != nil {
 			e := newError(ErrRequestError, fmt.Errorf("problem constructing request: %w", err))
 			if c.debug {
@@ -207,134 +279,169 @@ func (c *Client) doHttpRequest(ctx context.Context, body io.ReadSeeker) *rawGrap
 				e = e.withRequest(request, body)
 			}
 
+			endAttemptSpan(span, nil, Errors{e})
+
 			return &rawGraphQLResult{
 				Errors: Errors{e},
 			}
 		}
 
-		request.Header.Add("Content-Type", "application/json")
+		if input.contentType != "" {
+			request.Header.Add("Content-Type", input.contentType)
+		}
 
-		if c.requestModifier != nil {
-			c.requestModifier(request)
+		if contentEncoding != "" {
+			request.Header.Set("Content-Encoding", contentEncoding)
 		}
 
-		resp, err := c.httpClient.Do(request)
-		if err != nil {
-			e := newError(ErrRequestError, err)
+		if c.acceptEncoding != "" {
+			request.Header.Set("Accept-Encoding", c.acceptEncoding)
+		}
 
-			if c.debug {
-				_, _ = body.Seek(0, io.SeekStart)
-				e = e.withRequest(request, body)
+		for key, values := range input.header {
+			for _, value := range values {
+				request.Header.Add(key, value)
 			}
+		}
 
-			return &rawGraphQLResult{
-				Errors: Errors{e},
-			}
+		if request.Header.Get("Accept") == "" {
+			request.Header.Set("Accept", defaultAcceptHeader)
 		}
 
-		defer func() {
-			_ = resp.Body.Close()
-		}()
+		c.tracing.inject(attemptCtx, request.Header)
 
-		switch {
-		case resp.StatusCode >= 400:
-			if attempt >= c.maxRetries || !c.shouldRetryHttpError(resp) {
-				errorMessage, err := io.ReadAll(resp.Body)
-				if err != nil {
-					errorMessage = []byte(resp.Status)
-				}
+		if c.requestModifier != nil {
+			c.requestModifier(request)
+		}
 
-				gqlError := newError(ErrRequestError, NetworkError{
-					statusCode: resp.StatusCode,
-					body:       string(errorMessage),
-				})
+		resp, err := c.httpClient.Do(request)
+		recordAttempt(ctx, resp)
 
-				if c.debug {
-					_, _ = body.Seek(0, io.SeekStart)
-					gqlError = gqlError.withRequest(request, body)
-				}
+		if c.rateLimiter != nil {
+			c.rateLimiter.update(resp)
+		}
 
-				return &rawGraphQLResult{
-					Errors: Errors{gqlError},
-				}
-			}
-		case resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusCreated:
-			resp := c.decodeRawGraphQLResponse(request, body, resp)
-			if len(resp.Errors) == 0 || !resp.decoded {
-				return resp
+		if err != nil {
+			result = &rawGraphQLResult{
+				Errors: Errors{newError(ErrRequestError, err)},
 			}
+		} else {
+			result = c.readHTTPResponse(request, body, resp, input.batch)
+		}
 
-			if attempt >= c.maxRetries || c.retryOnGraphQLError == nil ||
-				!c.retryOnGraphQLError(resp.Errors) {
-				if c.debug &&
-					(resp.Errors[0].Extensions == nil || resp.Errors[0].Extensions["request"] == nil) {
-					_, _ = body.Seek(0, io.SeekStart)
-
-					if resp != nil && resp.responseBody != nil {
-						_, _ = resp.responseBody.Seek(0, io.SeekStart)
-					}
+		endAttemptSpan(span, resp, result.Errors)
+		c.recordAttemptResult(ctx, resp, result.Errors)
 
-					resp.Errors[0] = resp.Errors[0].
-						withRequest(request, body).
-						withResponse(resp.response, resp.responseBody)
-				}
+		// successful responses which can't be decoded aren't retried.
+		if len(result.Errors) == 0 || !canRetry || (resp != nil && resp.StatusCode < 400 && !result.decoded) {
+			return c.withDebugInfo(result, request, body)
+		}
 
-				return resp
-			}
-		default:
-			return &rawGraphQLResult{
-				Errors: Errors{
-					newError(
-						ErrRequestError,
-						fmt.Errorf("invalid HTTP status code: %d %s", resp.StatusCode, resp.Status),
-					),
-				},
-			}
+		delay, ok := c.retryPolicy.ShouldRetry(RetryAttempt{
+			OperationType: input.op.exported(),
+			Attempt:       attempt,
+			Elapsed:       time.Since(start),
+			Response:      resp,
+			Errors:        result.Errors,
+		})
+		if !ok {
+			return c.withDebugInfo(result, request, body)
 		}
 
-		time.Sleep(c.getRetryDelay(resp, attempt))
-	}
+		// stop retrying as soon as the context is done.
+		if err := sleepWithContext(ctx, delay); err != nil {
+			result.Errors = append(result.Errors, newError(ErrRequestError, err))
 
-	return &rawGraphQLResult{
-		Errors: Errors{newError(ErrRequestError, errors.New("unreachable code"))},
+			return result
+		}
 	}
 }
 
-// The HTTP [Retry-After] response header indicates how long the user agent should wait before making a follow-up request.
-// The client finds this header if exist and decodes to duration.
-// If the header doesn't exist or there is any error happened, fallback to the retry delay setting.
-//
-// [Retry-After]: https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Retry-After
-func (c *Client) getRetryDelay(resp *http.Response, attempts int) time.Duration {
-	if rawRetryAfter := resp.Header.Get("Retry-After"); rawRetryAfter != "" {
-		// A non-negative decimal integer indicating the seconds to delay after the response is received.
-		retryAfterSecs, err := strconv.ParseInt(rawRetryAfter, 10, 32)
-		if err == nil && retryAfterSecs > 0 {
-			return time.Duration(
-				math.Max(float64(int64(time.Second)*retryAfterSecs), float64(c.retryBaseDelay)),
-			)
+// readHTTPResponse decodes the HTTP response of the attempt and closes the body,
+// so bodies aren't held open across attempts.
+func (c *Client) readHTTPResponse(
+	request *http.Request,
+	body io.ReadSeeker,
+	resp *http.Response,
+	batch bool,
+) *rawGraphQLResult {
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch {
+	case resp.StatusCode >= 400:
+		return readErrorResponse(request, body, resp, batch)
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return c.decodeRawGraphQLResponse(request, body, resp, batch)
+	default:
+		return &rawGraphQLResult{
+			Errors: Errors{
+				newError(
+					ErrRequestError,
+					fmt.Errorf("invalid HTTP status code: %d %s", resp.StatusCode, resp.Status),
+				),
+			},
 		}
+	}
+}
+
+// withDebugInfo adds the request and response information to the first error of the final result in debug mode.
+func (c *Client) withDebugInfo(
+	result *rawGraphQLResult,
+	request *http.Request,
+	body io.ReadSeeker,
+) *rawGraphQLResult {
+	if !c.debug || len(result.Errors) == 0 || result.Errors[0].Extensions["request"] != nil {
+		return result
+	}
 
-		// A date after which to retry, e.g. Tue, 29 Oct 2024 16:56:32 GMT
-		retryTime, err := time.Parse(time.RFC1123, rawRetryAfter)
-		if err == nil && retryTime.After(time.Now()) {
-			duration := time.Until(retryTime)
+	_, _ = body.Seek(0, io.SeekStart)
 
-			return time.Duration(math.Max(float64(duration), float64(c.retryBaseDelay)))
+	switch {
+	case result.decoded:
+		if result.responseBody != nil {
+			_, _ = result.responseBody.Seek(0, io.SeekStart)
 		}
+
+		result.Errors[0] = result.Errors[0].
+			withRequest(request, body).
+			withResponse(result.response, result.responseBody)
+	case result.response == nil:
+		// the request failed or the HTTP status is an error.
+		result.Errors[0] = result.Errors[0].withRequest(request, body)
 	}
 
-	return time.Duration(
-		float64(c.retryBaseDelay) * math.Pow(c.retryExponentialRate, float64(attempts)),
-	)
+	return result
 }
 
 func (c *Client) decodeRawGraphQLResponse(
 	req *http.Request,
 	reqBody io.ReadSeeker,
 	resp *http.Response,
+	batch bool,
 ) *rawGraphQLResult {
-	var r io.Reader = resp.Body
+	respBody, err := decompressResponseBody(resp)
+	if err != nil {
+		return &rawGraphQLResult{
+			Errors: Errors{newError(ErrJsonDecode, err)},
+		}
+	}
+
+	defer func() {
+		_ = respBody.Close()
+	}()
+
+	if read := getResponseReader(req.Context()); read != nil && !batch {
+		out := read(resp, respBody)
+		out.request = req
+		out.requestBody = reqBody
+		out.response = resp
+
+		return out
+	}
+
+	var r io.Reader = respBody
 
 	// copy the response reader for debugging
 	var respReader *bytes.Reader
@@ -353,7 +460,12 @@ func (c *Client) decodeRawGraphQLResponse(
 
 	var out rawGraphQLResult
 
-	err := json.NewDecoder(r).Decode(&out)
+	if batch {
+		err = decodeBatchResponse(r, &out)
+	} else {
+		err = json.NewDecoder(r).Decode(&out)
+	}
+
 	out.request = req
 	out.requestBody = reqBody
 	out.response = resp
@@ -379,63 +491,200 @@ func (c *Client) decodeRawGraphQLResponse(
 	return &out
 }
 
-// shouldRetryHttpError determines whether the request should be retried by the http status code.
-func (c *Client) shouldRetryHttpError(resp *http.Response) bool {
-	for _, status := range c.retryHttpStatus {
-		if status == resp.StatusCode {
-			return true
+// doRequest sends graphql request through the middleware chain.
+func (c *Client) doRequest(
+	ctx context.Context,
+	op operationType,
+	query string,
+	variables map[string]any,
+	options *constructOptionsOutput,
+) *rawGraphQLResult {
+	req := newOperationRequest(op, query, variables, options)
+
+	ctx, metrics := c.startMetrics(ctx, req)
+	ctx, span := c.tracing.startOperation(ctx, req.OperationType, req.OperationName)
+	resp := c.handler()(ctx, req).result()
+	resp.metrics = metrics
+	endSpan(span, resp.Errors)
+
+	if options != nil && options.headers != nil && resp.response != nil {
+		for key, values := range resp.response.Header {
+			for _, value := range values {
+				options.headers.Add(key, value)
+			}
+		}
+	}
+
+	if len(resp.Data) == 0 {
+		resp.Data = nil
+	}
+
+	if len(resp.Extensions) == 0 {
+		resp.Extensions = nil
+	}
+
+	return resp
+}
+
+// execute is the innermost handler of the middleware chain which sends the request to the server.
+// Identical queries in flight share the result if the deduplication is enabled.
+func (c *Client) execute(ctx context.Context, req *Request) *Response {
+	// responses which are read as they arrive can't be shared, and operations of a batch can't wait for each other.
+	if c.deduplicator != nil && req.op == queryOperation && getResponseReader(ctx) == nil && getBatchMember(ctx) == nil {
+		return c.deduplicator.do(ctx, req, c.send)
+	}
+
+	return c.send(ctx, req)
+}
+
+// send routes the request to the batcher, the persisted query protocol or the regular request.
+func (c *Client) send(ctx context.Context, req *Request) *Response {
+	in := req.payload()
+	// files can only be sent in the multipart request of a single operation.
+	hasUploads := len(findUploads(in.Variables)) > 0
+
+	// batches which contain mutations aren't retried, so they don't need the idempotency key.
+	if member := getBatchMember(ctx); member != nil {
+		if hasUploads {
+			return newResponse(&rawGraphQLResult{
+				Errors: Errors{newError(ErrGraphQLEncode, errBatchUpload)},
+			})
+		}
+
+		return newResponse(member.batcher.do(&batchCall{
+			ctx:     ctx,
+			op:      req.op,
+			payload: in,
+			header:  req.Header,
+			member:  member,
+		}))
+	}
+
+	if req.op == mutationOperation && c.idempotencyKeyHeader != "" && req.Header.Get(c.idempotencyKeyHeader) == "" {
+		req.Header = req.Header.Clone()
+		if req.Header == nil {
+			req.Header = http.Header{}
 		}
+
+		req.Header.Set(c.idempotencyKeyHeader, uuid.NewString())
+	}
+
+	var resp *rawGraphQLResult
+
+	switch {
+	// batched requests are sent with POST, so queries aren't batched if they are sent with GET.
+	case c.batcher != nil && !c.useHTTPGet && req.op == queryOperation && len(req.Header) == 0 &&
+		getResponseReader(ctx) == nil && !hasUploads:
+		resp = c.batcher.do(&batchCall{
+			ctx:     ctx,
+			op:      req.op,
+			payload: in,
+		})
+	// files can be read only once, so operations with uploads are sent with the full query.
+	case c.persistedQueries && in.Query != "" && !hasUploads:
+		resp = c.sendRequest(ctx, req.op, newPersistedQueryPayload(in), req.Header)
+		// the server doesn't know the hash yet, resend the request with the full query.
+		if isPersistedQueryNotFound(resp.Errors) {
+			in.Extensions = newPersistedQueryPayload(in).Extensions
+			resp = c.sendRequest(ctx, req.op, in, req.Header)
+		}
+	default:
+		resp = c.sendRequest(ctx, req.op, in, req.Header)
 	}
 
-	return false
+	return newResponse(resp)
 }
 
-// doRequest sends graphql request.
-func (c *Client) doRequest(
+// sendRequest encodes the request payload and sends it to the server.
+// The multipart request is used if the variables contain files.
+// Query operations are sent with the GET method if enabled and the URL isn't too long.
+func (c *Client) sendRequest(
 	ctx context.Context,
-	query string,
-	variables map[string]any,
-	options *constructOptionsOutput,
+	op operationType,
+	payload GraphQLRequestPayload,
+	header http.Header,
 ) *rawGraphQLResult {
-	in := GraphQLRequestPayload{
-		Query:     query,
-		Variables: variables,
+	if files := findUploads(payload.Variables); len(files) > 0 {
+		return c.sendMultipartRequest(ctx, op, payload, files, header)
 	}
 
-	if options != nil {
-		in.OperationName = options.operationName
+	if c.useHTTPGet && op == queryOperation {
+		requestURL, err := c.buildGetRequestURL(payload)
+		if err != nil {
+			return &rawGraphQLResult{
+				Errors: Errors{newError(ErrGraphQLEncode, err)},
+			}
+		}
+
+		if len(requestURL) <= c.maxGetURLLength {
+			return c.doHttpRequest(ctx, graphqlHTTPRequest{
+				method: http.MethodGet,
+				url:    requestURL,
+				header: header,
+				body:   bytes.NewReader(nil),
+				op:     op,
+			})
+		}
 	}
 
 	var buf bytes.Buffer
 
-	err := json.NewEncoder(&buf).Encode(in)
+	err := json.NewEncoder(&buf).Encode(payload)
 	if err != nil {
 		return &rawGraphQLResult{
 			Errors: Errors{newError(ErrGraphQLEncode, err)},
 		}
 	}
 
-	reqReader := bytes.NewReader(buf.Bytes())
+	return c.doHttpRequest(ctx, graphqlHTTPRequest{
+		method:      http.MethodPost,
+		url:         c.url,
+		contentType: "application/json",
+		header:      header,
+		body:        bytes.NewReader(buf.Bytes()),
+		op:          op,
+	})
+}
 
-	resp := c.doHttpRequest(ctx, reqReader)
+// buildGetRequestURL encodes the request payload into the URL query parameters.
+// https://graphql.github.io/graphql-over-http/draft/#sec-GET
+func (c *Client) buildGetRequestURL(payload GraphQLRequestPayload) (string, error) {
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return "", err
+	}
 
-	if options != nil && options.headers != nil && resp.response != nil {
-		for key, values := range resp.response.Header {
-			for _, value := range values {
-				options.headers.Add(key, value)
-			}
-		}
+	params := u.Query()
+
+	if payload.Query != "" {
+		params.Set("query", payload.Query)
 	}
 
-	if len(resp.Data) == 0 {
-		resp.Data = nil
+	if payload.OperationName != "" {
+		params.Set("operationName", payload.OperationName)
 	}
 
-	if len(resp.Extensions) == 0 {
-		resp.Extensions = nil
+	if len(payload.Variables) > 0 {
+		variables, err := json.Marshal(payload.Variables)
+		if err != nil {
+			return "", err
+		}
+
+		params.Set("variables", string(variables))
 	}
 
-	return resp
+	if len(payload.Extensions) > 0 {
+		extensions, err := json.Marshal(payload.Extensions)
+		if err != nil {
+			return "", err
+		}
+
+		params.Set("extensions", string(extensions))
+	}
+
+	u.RawQuery = params.Encode()
+
+	return u.String(), nil
 }
 
 // return raw message and error.
@@ -451,7 +700,9 @@ func (c *Client) doRaw(
 		return nil, err
 	}
 
-	resp := c.doRequest(ctx, query, variables, optionsOutput)
+	resp := c.doRequest(ctx, op, query, variables, optionsOutput)
+	resp.metrics.finish(resp.Errors)
+
 	if len(resp.Errors) > 0 {
 		return resp.Data, resp.Errors
 	}
@@ -472,9 +723,20 @@ func (c *Client) do(
 		return err
 	}
 
-	resp := c.doRequest(ctx, query, variables, optionsOutput)
+	if c.normalizedCache != nil && op == queryOperation {
+		if data, ok := c.normalizedCache.read(v, variables); ok {
+			return c.processResponse(v, &rawGraphQLResult{Data: data}, optionsOutput)
+		}
+	}
+
+	resp := c.doRequest(c.withStreamTarget(ctx, op, v, optionsOutput), op, query, variables, optionsOutput)
+
+	// partial data with errors isn't cached, null fields may be caused by errors.
+	if c.normalizedCache != nil && len(resp.Data) > 0 && len(resp.Errors) == 0 {
+		c.normalizedCache.write(op, v, variables, resp.Data)
+	}
 
-	return c.processResponse(v, resp, optionsOutput.extensions)
+	return c.processResponse(v, resp, optionsOutput)
 }
 
 // Executes a pre-built query and unmarshals the response into v. Unlike the Query method you have to specify in the query the
@@ -491,9 +753,10 @@ func (c *Client) Exec(
 		return err
 	}
 
-	resp := c.doRequest(ctx, query, variables, optionsOutput)
+	op := parseOperationType(query)
+	resp := c.doRequest(c.withStreamTarget(ctx, op, v, optionsOutput), op, query, variables, optionsOutput)
 
-	return c.processResponse(v, resp, optionsOutput.extensions)
+	return c.processResponse(v, resp, optionsOutput)
 }
 
 // Executes a pre-built query and returns the raw json message. Unlike the Query method you have to specify in the query the
@@ -509,7 +772,9 @@ func (c *Client) ExecRaw(
 		return nil, err
 	}
 
-	resp := c.doRequest(ctx, query, variables, optionsOutput)
+	resp := c.doRequest(ctx, parseOperationType(query), query, variables, optionsOutput)
+	resp.metrics.finish(resp.Errors)
+
 	if len(resp.Errors) > 0 {
 		return resp.Data, resp.Errors
 	}
@@ -531,7 +796,9 @@ func (c *Client) ExecRawWithExtensions(
 		return nil, nil, err
 	}
 
-	resp := c.doRequest(ctx, query, variables, optionsOutput)
+	resp := c.doRequest(ctx, parseOperationType(query), query, variables, optionsOutput)
+	resp.metrics.finish(resp.Errors)
+
 	if len(resp.Errors) > 0 {
 		return resp.Data, resp.Extensions, resp.Errors
 	}
@@ -539,10 +806,22 @@ func (c *Client) ExecRawWithExtensions(
 	return resp.Data, resp.Extensions, nil
 }
 
-func (c *Client) processResponse(v any, resp *rawGraphQLResult, extensions any) error {
+func (c *Client) processResponse(v any, resp *rawGraphQLResult, options *constructOptionsOutput) error {
+	policy := c.getErrorPolicy(options)
 	errs := resp.Errors
+	// errors of the response with partial data are only bound with the ignore policy.
+	ignoredErrors := 0
+	if policy == ErrorPolicyIgnore && resp.hasData() {
+		ignoredErrors = len(resp.Errors)
+	}
 
-	if len(resp.Data) > 0 {
+	if resp.streamErr != nil {
+		errs = append(errs, newError(ErrGraphQLDecode, resp.streamErr))
+	}
+
+	// the struct is left untouched if the response has errors with the none policy.
+	decodeData := len(resp.Data) > 0 && (policy != ErrorPolicyNone || len(errs) == 0)
+	if decodeData {
 		err := jsonutil.UnmarshalGraphQL(resp.Data, v)
 		if err != nil {
 			we := newError(ErrGraphQLDecode, err)
@@ -555,16 +834,30 @@ func (c *Client) processResponse(v any, resp *rawGraphQLResult, extensions any)
 		}
 	}
 
-	if len(resp.Extensions) > 0 && extensions != nil {
-		err := json.Unmarshal(resp.Extensions, extensions)
+	if len(resp.Extensions) > 0 && options != nil && options.extensions != nil {
+		err := json.Unmarshal(resp.Extensions, options.extensions)
 		if err != nil {
 			we := newError(ErrGraphQLExtensionsDecode, err)
 			errs = append(errs, we)
 		}
 	}
 
-	if len(errs) > 0 {
-		return errs
+	resp.metrics.finish(errs)
+
+	// the struct is filled by the decoded data, or the data of the response stream.
+	if len(errs) > 0 && (decodeData || resp.streamedData) {
+		errs.resolveFields(v)
+	}
+
+	if options != nil && options.errors != nil {
+		*options.errors = nil
+		if len(errs) > 0 {
+			*options.errors = errs
+		}
+	}
+
+	if len(errs) > ignoredErrors {
+		return errs[ignoredErrors:]
 	}
 
 	return nil
@@ -574,6 +867,7 @@ func (c *Client) processResponse(v any, resp *rawGraphQLResult, extensions any)
 func (c *Client) WithRequestModifier(f RequestModifier) *Client {
 	newClient := *c
 	newClient.requestModifier = f
+	newClient.rebind()
 
 	return &newClient
 }
@@ -582,10 +876,23 @@ func (c *Client) WithRequestModifier(f RequestModifier) *Client {
 func (c *Client) WithDebug(debug bool) *Client {
 	newClient := *c
 	newClient.debug = debug
+	newClient.rebind()
 
 	return &newClient
 }
 
+// rebind creates a new query batcher and deduplicator for the copied client,
+// so requests are sent with the settings of the copy and results aren't shared with the original client.
+func (c *Client) rebind() {
+	if c.batcher != nil {
+		c.batcher = newQueryBatcher(c)
+	}
+
+	if c.deduplicator != nil {
+		c.deduplicator = newQueryDeduplicator()
+	}
+}
+
 // errors represents the "errors" array in a response from a GraphQL server.
 // If returned via error interface, the slice is expected to contain at least 1 element.
 //
@@ -601,6 +908,8 @@ type Error struct {
 	} `json:"locations"`
 	Path []any `json:"path"`
 	err  error
+	// fields of the decoded value which the path points to
+	fields []reflect.Value
 }
 
 // Error implements error interface.
@@ -630,10 +939,13 @@ func (e Errors) Error() string {
 }
 
 // Unwrap implements the error unwrap interface.
+// It returns the underlying errors of the client, e.g. NetworkError, so they can be found with errors.As.
 func (e Errors) Unwrap() []error {
-	errs := make([]error, len(e))
-	for i, err := range e {
-		errs[i] = err.err
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		if err.err != nil {
+			errs = append(errs, err.err)
+		}
 	}
 
 	return errs
@@ -726,12 +1038,15 @@ func UnmarshalGraphQL(data []byte, v any) error {
 	return jsonutil.UnmarshalGraphQL(data, v)
 }
 
+// default max length of the GET request URL.
+const defaultMaxGetURLLength = 2048
+
 type operationType uint8
 
 const (
 	queryOperation operationType = iota
 	mutationOperation
-	// subscriptionOperation // Unused.
+	subscriptionOperation
 )
 
 const (
@@ -741,6 +1056,7 @@ const (
 	ErrGraphQLEncode           = "graphql_encode_error"
 	ErrGraphQLDecode           = "graphql_decode_error"
 	ErrGraphQLExtensionsDecode = "graphql_extensions_decode_error"
+	ErrCircuitOpen             = "circuit_open_error"
 )
 
 type rawGraphQLResult struct {
@@ -748,6 +1064,17 @@ type rawGraphQLResult struct {
 	Extensions json.RawMessage `json:"extensions"`
 	Errors     Errors          `json:"errors"`
 
+	// results of batched operations
+	batch []rawGraphQLResult
+
+	// metrics of the operation, recorded after the response is processed
+	metrics *operationMetrics
+
+	// the error of the data which is decoded from the response stream
+	streamErr error
+	// the non-null data is decoded from the response stream into the target, so Data is empty
+	streamedData bool
+
 	// request and response information
 	decoded      bool
 	request      *http.Request
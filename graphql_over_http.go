@@ -0,0 +1,69 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+)
+
+const (
+	// the media type of GraphQL responses of the GraphQL-over-HTTP specification.
+	graphqlResponseMediaType = "application/graphql-response+json"
+	// the default Accept header of requests, which prefers the media type of the specification
+	// and falls back to application/json of legacy servers.
+	defaultAcceptHeader = graphqlResponseMediaType + ", application/json;q=0.9"
+)
+
+// readErrorResponse reads the response of a non-successful HTTP status.
+// Servers which follow the GraphQL-over-HTTP specification respond well-formed GraphQL responses with 4xx and 5xx statuses,
+// e.g. 400 if the request isn't valid, so the errors of the body are returned as GraphQL errors.
+// The HTTP status is kept as the NetworkError of each error. Other bodies are returned as a NetworkError.
+func readErrorResponse(
+	request *http.Request,
+	body io.ReadSeeker,
+	resp *http.Response,
+	batch bool,
+) *rawGraphQLResult {
+	errorMessage, err := readErrorBody(resp)
+	if err != nil {
+		errorMessage = []byte(resp.Status)
+	}
+
+	networkErr := NetworkError{
+		statusCode: resp.StatusCode,
+		body:       string(errorMessage),
+	}
+
+	if !batch && err == nil && isGraphQLResponseMediaType(resp.Header.Get("Content-Type")) {
+		var out rawGraphQLResult
+		if json.Unmarshal(errorMessage, &out) == nil && len(out.Errors) > 0 {
+			for i := range out.Errors {
+				out.Errors[i].err = networkErr
+			}
+
+			out.decoded = true
+			out.request = request
+			out.requestBody = body
+			out.response = resp
+			out.responseBody = bytes.NewReader(errorMessage)
+
+			return &out
+		}
+	}
+
+	return &rawGraphQLResult{
+		Errors: Errors{newError(ErrRequestError, networkErr)},
+	}
+}
+
+// isGraphQLResponseMediaType reports whether the content type may be a GraphQL response.
+func isGraphQLResponseMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == graphqlResponseMediaType || mediaType == "application/json"
+}
@@ -0,0 +1,149 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+func TestClient_acceptHeader(t *testing.T) {
+	var accept string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		accept = req.Header.Get("Accept")
+		w.Header().Set("Content-Type", "application/graphql-response+json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	if err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "application/graphql-response+json, application/json;q=0.9"; accept != want {
+		t.Errorf("got Accept: %q, want: %q", accept, want)
+	}
+	if q.User.Name != "Gopher" {
+		t.Errorf("got name: %q, want: Gopher", q.User.Name)
+	}
+}
+
+func TestClient_graphqlOverHTTPStatus(t *testing.T) {
+	testCases := []struct {
+		name        string
+		status      int
+		contentType string
+		response    string
+		message     string
+		code        string
+		class       graphql.ErrorClass
+	}{
+		{
+			name:        "validation error",
+			status:      http.StatusBadRequest,
+			contentType: "application/graphql-response+json; charset=utf-8",
+			response:    `{"errors": [{"message": "unknown field", "extensions": {"code": "GRAPHQL_VALIDATION_FAILED"}}]}`,
+			message:     "unknown field",
+			code:        "GRAPHQL_VALIDATION_FAILED",
+			class:       graphql.ErrorClassValidation,
+		},
+		{
+			name:        "legacy server",
+			status:      http.StatusUnauthorized,
+			contentType: "application/json",
+			response:    `{"errors": [{"message": "not logged in"}]}`,
+			message:     "not logged in",
+			class:       graphql.ErrorClassUnauthenticated,
+		},
+		{
+			name:        "not a graphql response",
+			status:      http.StatusBadRequest,
+			contentType: "application/json",
+			response:    `{"error": "bad request"}`,
+			message:     "400 Bad Request",
+			code:        graphql.ErrRequestError,
+			class:       graphql.ErrorClassValidation,
+		},
+		{
+			name:        "proxy error",
+			status:      http.StatusBadGateway,
+			contentType: "text/html",
+			response:    `<html>bad gateway</html>`,
+			message:     "502 Bad Gateway",
+			code:        graphql.ErrRequestError,
+			class:       graphql.ErrorClassTransient,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", tc.contentType)
+				w.WriteHeader(tc.status)
+				mustWrite(w, tc.response)
+			})
+
+			client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+			var q struct {
+				User struct {
+					Name string
+				}
+			}
+			err := client.Query(context.Background(), &q, nil)
+
+			var errs graphql.Errors
+			if !errors.As(err, &errs) || len(errs) != 1 {
+				t.Fatalf("got error: %v, want 1 error", err)
+			}
+			if errs[0].Message != tc.message {
+				t.Errorf("got message: %q, want: %q", errs[0].Message, tc.message)
+			}
+			if code := errs[0].Code(); code != tc.code {
+				t.Errorf("got code: %q, want: %q", code, tc.code)
+			}
+			if class := errs[0].Class(); class != tc.class {
+				t.Errorf("got class: %q, want: %q", class, tc.class)
+			}
+
+			var networkErr graphql.NetworkError
+			if !errors.As(err, &networkErr) || networkErr.StatusCode() != tc.status || networkErr.Body() != tc.response {
+				t.Errorf("got network error: %v, want: %d", networkErr, tc.status)
+			}
+		})
+	}
+}
+
+func TestClient_successfulStatus(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/graphql-response+json")
+		w.WriteHeader(http.StatusAccepted)
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	if err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if q.User.Name != "Gopher" {
+		t.Errorf("got name: %q, want: Gopher", q.User.Name)
+	}
+}
@@ -648,6 +648,83 @@ func TestClient_Exec_QueryRawWithExtensions(t *testing.T) {
 	}
 }
 
+func TestClient_Query_httpGet(t *testing.T) {
+	var methods []string
+	var requests []*http.Request
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		methods = append(methods, req.Method)
+		requests = append(requests, req)
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+	client := graphql.NewClient(
+		"/graphql?tenant=foo",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithHTTPGet(0),
+	)
+
+	var q struct {
+		User struct {
+			Name string
+		} `graphql:"user(id: $id)"`
+	}
+
+	err := client.Query(context.Background(), &q, map[string]any{
+		"id": graphql.ID("1"),
+	}, graphql.OperationName("GetUser"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 1 || methods[0] != http.MethodGet {
+		t.Fatalf("got methods: %v, want: [GET]", methods)
+	}
+	params := requests[0].URL.Query()
+	if got, want := params.Get("query"), "query GetUser($id:ID!){user(id: $id){name}}"; got != want {
+		t.Errorf("got query: %q, want: %q", got, want)
+	}
+	if got, want := params.Get("variables"), `{"id":"1"}`; got != want {
+		t.Errorf("got variables: %q, want: %q", got, want)
+	}
+	if got, want := params.Get("operationName"), "GetUser"; got != want {
+		t.Errorf("got operationName: %q, want: %q", got, want)
+	}
+	if got, want := params.Get("tenant"), "foo"; got != want {
+		t.Errorf("got tenant: %q, want: %q", got, want)
+	}
+	if got := requests[0].Header.Get("Content-Type"); got != "" {
+		t.Errorf("got Content-Type: %q, want: empty", got)
+	}
+
+	// mutations are always sent with POST
+	var m struct {
+		User struct {
+			Name string
+		} `graphql:"user: updateUser"`
+	}
+	if err := client.Mutate(context.Background(), &m, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ExecRaw(context.Background(), "mutation {user: updateUser{name}}", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// fallback to POST if the URL is too long
+	shortURLClient := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithHTTPGet(10),
+	)
+	if err := shortURLClient.Query(context.Background(), &q, map[string]any{"id": graphql.ID("1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := methods, []string{http.MethodGet, http.MethodPost, http.MethodPost, http.MethodPost}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got methods: %v, want: %v", got, want)
+	}
+}
+
 // localRoundTripper is an http.RoundTripper that executes HTTP transactions
 // by using handler directly, instead of going over an HTTP connection.
 type localRoundTripper struct {
@@ -0,0 +1,496 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+
+	"github.com/hasura/go-graphql-client/pkg/jsonutil"
+)
+
+// Incremental delivery sends the result of @defer and @stream directives in many payloads
+// of the multipart/mixed response.
+// https://github.com/graphql/graphql-over-http/blob/main/rfcs/IncrementalDelivery.md
+
+const incrementalAcceptHeader = "multipart/mixed;deferSpec=20220824, application/json"
+
+// IncrementalResult is a payload of the incremental delivery response.
+// The first result contains the initial data, the next results contain
+// the data of deferred fragments or the items of streamed lists.
+type IncrementalResult struct {
+	// Path of the deferred fragment or the first streamed item in the response data, empty for the initial result.
+	Path []any `json:"path"`
+	// Label of the @defer or @stream directive.
+	Label string `json:"label"`
+	// Data of the initial result or the deferred fragment.
+	Data json.RawMessage `json:"data"`
+	// Items of the streamed list.
+	Items      json.RawMessage `json:"items"`
+	Errors     Errors          `json:"errors"`
+	Extensions json.RawMessage `json:"extensions"`
+	// HasNext is false if this is the last result of the response.
+	HasNext bool `json:"-"`
+}
+
+// IncrementalHandler is called for every result of the incremental delivery response,
+// after the result is patched into the target struct.
+// Return an error to stop reading the response.
+type IncrementalHandler func(result IncrementalResult) error
+
+// incrementalPayload is a part of the multipart/mixed response.
+type incrementalPayload struct {
+	IncrementalResult
+	Incremental []IncrementalResult `json:"incremental"`
+	HasNext     bool                `json:"hasNext"`
+}
+
+// QueryIncremental executes a query which uses @defer or @stream directives.
+// The initial result and every incremental result are patched into q at their path, then the handler is called,
+// so slow fields don't block the first result. q should be a pointer to struct that corresponds to the GraphQL schema.
+// The method returns when the server sent the last result. GraphQL errors of all results are returned together.
+//
+// The operation goes through middlewares, the retry policy and other request options of the client.
+// Results which were delivered before an error of the response aren't rolled back,
+// they can be delivered again if the retry policy retries the operation.
+func (c *Client) QueryIncremental(
+	ctx context.Context,
+	q any,
+	variables map[string]any,
+	handler IncrementalHandler,
+	options ...Option,
+) error {
+	query, optionsOutput, err := c.buildQueryAndOptions(queryOperation, q, variables, options...)
+	if err != nil {
+		return err
+	}
+
+	return c.doIncremental(ctx, queryOperation, query, q, variables, handler, optionsOutput)
+}
+
+// ExecIncremental executes a pre-built query which uses @defer or @stream directives,
+// and patches the results into v. See QueryIncremental.
+func (c *Client) ExecIncremental(
+	ctx context.Context,
+	query string,
+	v any,
+	variables map[string]any,
+	handler IncrementalHandler,
+	options ...Option,
+) error {
+	optionsOutput, err := constructOptions(options)
+	if err != nil {
+		return err
+	}
+
+	return c.doIncremental(ctx, parseOperationType(query), query, v, variables, handler, optionsOutput)
+}
+
+func (c *Client) doIncremental(
+	ctx context.Context,
+	op operationType,
+	query string,
+	v any,
+	variables map[string]any,
+	handler IncrementalHandler,
+	options *constructOptionsOutput,
+) error {
+	var patcher *incrementalPatcher
+
+	// every attempt is read by a new patcher, which resets the target with the initial result.
+	read := func(resp *http.Response, body io.Reader) *rawGraphQLResult {
+		patcher = newIncrementalPatcher(v, handler, options.extensions)
+		err := patcher.read(resp.Header.Get("Content-Type"), body)
+
+		return patcher.rawResult(err)
+	}
+
+	requestOptions := *options
+	requestOptions.requestHeader = http.Header{"Accept": {incrementalAcceptHeader}}
+	resp := c.doRequest(withResponseReader(ctx, read), op, query, variables, &requestOptions)
+
+	// the response isn't read from the server if a middleware responds by itself, e.g. the response cache.
+	if patcher == nil && len(resp.Data) > 0 {
+		patcher = newIncrementalPatcher(v, handler, options.extensions)
+		err := patcher.apply(incrementalPayload{
+			IncrementalResult: IncrementalResult{
+				Data:       resp.Data,
+				Errors:     resp.Errors,
+				Extensions: resp.Extensions,
+			},
+		})
+		resp.Errors = patcher.rawResult(err).Errors
+	}
+
+	resp.metrics.finish(resp.Errors)
+
+	if patcher != nil && patcher.handlerErr != nil {
+		return patcher.handlerErr
+	}
+
+	if len(resp.Errors) > 0 {
+		return resp.Errors
+	}
+
+	return nil
+}
+
+// incrementalPatcher merges incremental results into the response data and decodes them into the target.
+type incrementalPatcher struct {
+	target     any
+	handler    IncrementalHandler
+	extensions any
+	data       any
+	errs       Errors
+	// the error of the handler, which stops reading the response
+	handlerErr error
+}
+
+func newIncrementalPatcher(target any, handler IncrementalHandler, extensions any) *incrementalPatcher {
+	return &incrementalPatcher{
+		target:     target,
+		handler:    handler,
+		extensions: extensions,
+	}
+}
+
+// read applies payloads of the response body until the last result.
+func (ip *incrementalPatcher) read(contentType string, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/mixed" {
+		// the server doesn't support incremental delivery and replies the whole result.
+		var p incrementalPayload
+		if err := json.NewDecoder(body).Decode(&p); err != nil {
+			return Errors{newError(ErrJsonDecode, err)}
+		}
+
+		// the request is sent again with the full query, see Client.send.
+		if isPersistedQueryNotFound(p.Errors) {
+			ip.errs = p.Errors
+
+			return nil
+		}
+
+		p.HasNext = false
+
+		return ip.apply(p)
+	}
+
+	boundary := params["boundary"]
+	if boundary == "" {
+		boundary = "-"
+	}
+
+	reader := multipart.NewReader(body, boundary)
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return Errors{newError(ErrJsonDecode, err)}
+		}
+
+		var p incrementalPayload
+
+		err = json.NewDecoder(part).Decode(&p)
+		if errors.Is(err, io.EOF) {
+			// empty parts are used to keep the connection alive.
+			continue
+		}
+
+		if err != nil {
+			return Errors{newError(ErrJsonDecode, err)}
+		}
+
+		if err := ip.apply(p); err != nil {
+			return err
+		}
+
+		if !p.HasNext {
+			return nil
+		}
+	}
+}
+
+// rawResult returns the result of the read response with the error of reading.
+// The result isn't retried if the response can't be read, or the handler fails.
+func (ip *incrementalPatcher) rawResult(err error) *rawGraphQLResult {
+	out := &rawGraphQLResult{
+		Errors:  ip.errs,
+		decoded: err == nil,
+	}
+
+	var errs Errors
+	if ip.handlerErr == nil && errors.As(err, &errs) {
+		out.Errors = append(out.Errors, errs...)
+	}
+
+	return out
+}
+
+// apply patches the results of the payload and calls the handler.
+func (ip *incrementalPatcher) apply(p incrementalPayload) error {
+	var results []IncrementalResult
+
+	switch {
+	case len(p.Incremental) > 0:
+		results = p.Incremental
+	case ip.data == nil || len(p.Path) > 0:
+		// the initial result, or a subsequent result of the legacy format without the incremental array.
+		results = []IncrementalResult{p.IncrementalResult}
+	case len(p.Errors) > 0 || len(p.Extensions) > 0:
+		// the last payload may only contain errors and extensions.
+		results = []IncrementalResult{p.IncrementalResult}
+	}
+
+	for i := range results {
+		result := &results[i]
+		result.HasNext = p.HasNext
+
+		if err := ip.patch(result); err != nil {
+			return Errors{newError(ErrGraphQLDecode, err)}
+		}
+
+		ip.errs = append(ip.errs, result.Errors...)
+
+		if len(result.Extensions) > 0 && ip.extensions != nil {
+			if err := json.Unmarshal(result.Extensions, ip.extensions); err != nil {
+				return Errors{newError(ErrGraphQLExtensionsDecode, err)}
+			}
+		}
+	}
+
+	if ip.handler == nil {
+		return nil
+	}
+
+	for _, result := range results {
+		if err := ip.handler(result); err != nil {
+			ip.handlerErr = err
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// patch merges the data or items of the result into the response data at the result path,
+// and decodes the patched value into the target.
+func (ip *incrementalPatcher) patch(result *IncrementalResult) error {
+	switch {
+	case len(result.Items) > 0:
+		var items []json.RawMessage
+		if err := json.Unmarshal(result.Items, &items); err != nil {
+			return fmt.Errorf("expected the streamed items at %v to be a list: %w", result.Path, err)
+		}
+
+		if len(result.Path) == 0 {
+			return errors.New("the path of streamed items is empty")
+		}
+
+		index, ok := jsonutil.PathIndex(result.Path[len(result.Path)-1])
+		if !ok || index < 0 {
+			return fmt.Errorf("invalid index of streamed items: %v", result.Path)
+		}
+
+		values := make([]any, len(items))
+		for i, item := range items {
+			value, err := decodeJSONValue(item)
+			if err != nil {
+				return err
+			}
+
+			values[i] = value
+		}
+
+		listPath := result.Path[:len(result.Path)-1]
+
+		var err error
+
+		ip.data, err = patchJSONValue(ip.data, listPath, func(value any) (any, error) {
+			current, ok := value.([]any)
+			if !ok && value != nil {
+				return nil, fmt.Errorf("expected a list at %v", result.Path)
+			}
+
+			if index > len(current) {
+				return nil, fmt.Errorf("index of streamed items %d is out of range %d", index, len(current))
+			}
+
+			return append(current[:index], values...), nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return ip.decodeItems(listPath, index, items)
+	case len(result.Data) > 0 && !bytes.Equal(bytes.TrimSpace(result.Data), []byte("null")):
+		data, err := decodeJSONValue(result.Data)
+		if err != nil {
+			return err
+		}
+
+		var merged any
+
+		ip.data, err = patchJSONValue(ip.data, result.Path, func(value any) (any, error) {
+			merged = mergeJSONValue(value, data)
+
+			return merged, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return ip.decodeValue(result.Path, merged)
+	default:
+		return nil
+	}
+}
+
+// decodeValue decodes the merged value into the field of the target at the path,
+// so the rest of the target isn't decoded again.
+func (ip *incrementalPatcher) decodeValue(path []any, value any) error {
+	if len(path) == 0 {
+		return ip.decode()
+	}
+
+	fields := jsonutil.ResolvePath(ip.target, path)
+	if len(fields) != len(path) {
+		return ip.decode()
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	field := fields[len(fields)-1]
+	field.Set(reflect.Zero(field.Type()))
+
+	return jsonutil.UnmarshalGraphQL(data, field.Addr().Interface())
+}
+
+// decodeItems appends the streamed items to the list of the target at the path,
+// so items which were delivered before aren't decoded again.
+func (ip *incrementalPatcher) decodeItems(path []any, index int, items []json.RawMessage) error {
+	fields := jsonutil.ResolvePath(ip.target, path)
+	if len(path) == 0 || len(fields) != len(path) {
+		return ip.decode()
+	}
+
+	list := fields[len(fields)-1]
+	for list.Kind() == reflect.Ptr && !list.IsNil() {
+		list = list.Elem()
+	}
+
+	if list.Kind() != reflect.Slice || list.Len() != index {
+		return ip.decode()
+	}
+
+	for _, item := range items {
+		elem := reflect.New(list.Type().Elem())
+		if err := jsonutil.UnmarshalGraphQL(item, elem.Interface()); err != nil {
+			return err
+		}
+
+		list.Set(reflect.Append(list, elem.Elem()))
+	}
+
+	return nil
+}
+
+// decode writes the whole merged response data into the target.
+func (ip *incrementalPatcher) decode() error {
+	data, err := json.Marshal(ip.data)
+	if err != nil {
+		return err
+	}
+
+	// reset the target so lists aren't decoded twice.
+	target := reflect.ValueOf(ip.target)
+	if target.Kind() == reflect.Ptr && !target.IsNil() {
+		target.Elem().Set(reflect.Zero(target.Elem().Type()))
+	}
+
+	return jsonutil.UnmarshalGraphQL(data, ip.target)
+}
+
+// decodeJSONValue decodes the raw JSON value and keeps numbers as they are.
+func decodeJSONValue(raw json.RawMessage) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var value any
+	err := decoder.Decode(&value)
+
+	return value, err
+}
+
+// patchJSONValue replaces the value at the path of root with the result of fn.
+func patchJSONValue(root any, path []any, fn func(value any) (any, error)) (any, error) {
+	if len(path) == 0 {
+		return fn(root)
+	}
+
+	switch node := root.(type) {
+	case map[string]any:
+		key, ok := path[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("expected an object key in the path, got %v", path[0])
+		}
+
+		value, err := patchJSONValue(node[key], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+
+		node[key] = value
+
+		return node, nil
+	case []any:
+		index, ok := jsonutil.PathIndex(path[0])
+		if !ok || index < 0 || index >= len(node) {
+			return nil, fmt.Errorf("invalid list index in the path: %v", path[0])
+		}
+
+		value, err := patchJSONValue(node[index], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+
+		node[index] = value
+
+		return node, nil
+	default:
+		return nil, fmt.Errorf("can't find the path %v in the response data", path)
+	}
+}
+
+// mergeJSONValue deep merges the source object into the destination object.
+func mergeJSONValue(dst, src any) any {
+	dstObject, ok := dst.(map[string]any)
+	if !ok {
+		return src
+	}
+
+	srcObject, ok := src.(map[string]any)
+	if !ok {
+		return src
+	}
+
+	for key, value := range srcObject {
+		dstObject[key] = mergeJSONValue(dstObject[key], value)
+	}
+
+	return dstObject
+}
@@ -0,0 +1,330 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+func TestClient_QueryIncremental(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Header.Get("Accept"), "multipart/mixed;deferSpec=20220824, application/json"; got != want {
+			t.Errorf("got Accept: %q, want: %q", got, want)
+		}
+		var payload graphql.GraphQLRequestPayload
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := payload.Query, `{user{name,friends @stream(initialCount: 1){name},... @defer(label: "slow"){bio}}}`; got != want {
+			t.Errorf("got query: %q, want: %q", got, want)
+		}
+
+		writer := multipart.NewWriter(w)
+		w.Header().Set("Content-Type", `multipart/mixed; boundary="`+writer.Boundary()+`"`)
+		for _, part := range []string{
+			`{"data": {"user": {"name": "Gopher", "friends": [{"name": "a"}]}}, "hasNext": true}`,
+			`{"incremental": [{"data": {"bio": "slow bio"}, "path": ["user"], "label": "slow"}], "hasNext": true}`,
+			`{"incremental": [{"items": [{"name": "b"}, {"name": "c"}], "path": ["user", "friends", 1]}], "hasNext": false}`,
+		} {
+			pw, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			mustWrite(pw, part)
+		}
+		_ = writer.Close()
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	type friend struct {
+		Name string
+	}
+	var q struct {
+		User struct {
+			Name       string
+			Friends    []friend `graphql:"friends @stream(initialCount: 1)"`
+			SlowFields struct {
+				Bio string
+			} `graphql:"... @defer(label: \"slow\")"`
+		}
+	}
+
+	var labels []string
+	var friends [][]friend
+	var bios []string
+	err := client.QueryIncremental(context.Background(), &q, nil, func(result graphql.IncrementalResult) error {
+		labels = append(labels, result.Label)
+		friends = append(friends, append([]friend(nil), q.User.Friends...))
+		bios = append(bios, q.User.SlowFields.Bio)
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := labels, []string{"", "slow", ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got labels: %v, want: %v", got, want)
+	}
+	if got, want := bios, []string{"", "slow bio", "slow bio"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got bios: %v, want: %v", got, want)
+	}
+	wantFriends := [][]friend{
+		{{Name: "a"}},
+		{{Name: "a"}},
+		{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+	}
+	if !reflect.DeepEqual(friends, wantFriends) {
+		t.Errorf("got friends: %v, want: %v", friends, wantFriends)
+	}
+	if q.User.Name != "Gopher" {
+		t.Errorf("got q.User.Name: %q, want: Gopher", q.User.Name)
+	}
+}
+
+func TestClient_QueryIncremental_notSupported(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}, "errors": [{"message": "partial", "path": ["user", "bio"]}]}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+
+	var calls int
+	err := client.QueryIncremental(context.Background(), &q, nil, func(result graphql.IncrementalResult) error {
+		calls++
+		if result.HasNext {
+			t.Error("got HasNext: true, want: false")
+		}
+
+		return nil
+	})
+	if err == nil || err.Error() != "Message: partial, Locations: [], Extensions: map[], Path: [user bio]" {
+		t.Errorf("got error: %v, want: partial", err)
+	}
+	if calls != 1 || q.User.Name != "Gopher" {
+		t.Errorf("got calls: %d, name: %q, want: 1, Gopher", calls, q.User.Name)
+	}
+}
+
+func writeIncrementalParts(t *testing.T, w http.ResponseWriter, parts ...string) {
+	t.Helper()
+
+	writer := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", `multipart/mixed; boundary="`+writer.Boundary()+`"`)
+	for _, part := range parts {
+		pw, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustWrite(pw, part)
+	}
+	_ = writer.Close()
+}
+
+func TestClient_QueryIncremental_requestOptions(t *testing.T) {
+	attempts := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		attempts++
+		if attempts == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+
+			return
+		}
+
+		writeIncrementalParts(t, w,
+			`{"data": {"user": {"name": "Gopher", "friends": [{"name": "a"}]}}, "hasNext": true}`,
+			`{"incremental": [{"items": [{"name": "b"}], "path": ["user", "friends", 1]}], "hasNext": false}`,
+		)
+	})
+
+	policy := graphql.NewBackoffRetryPolicy(1)
+	policy.BaseDelay = time.Millisecond
+
+	var operations []graphql.OperationType
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRetryPolicy(policy),
+		graphql.WithMiddleware(func(next graphql.Handler) graphql.Handler {
+			return func(ctx context.Context, req *graphql.Request) *graphql.Response {
+				operations = append(operations, req.OperationType)
+
+				return next(ctx, req)
+			}
+		}),
+	)
+
+	var q struct {
+		User struct {
+			Name    string
+			Friends []struct {
+				Name string
+			} `graphql:"friends @stream(initialCount: 1)"`
+		}
+	}
+
+	results := 0
+	header := http.Header{}
+	err := client.QueryIncremental(context.Background(), &q, nil, func(result graphql.IncrementalResult) error {
+		results++
+
+		return nil
+	}, graphql.BindResponseHeaders(&header))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if attempts != 2 || results != 2 {
+		t.Errorf("got attempts: %d, results: %d, want: 2, 2", attempts, results)
+	}
+	if len(operations) != 1 || operations[0] != graphql.OperationTypeQuery {
+		t.Errorf("got middleware operations: %v, want: [query]", operations)
+	}
+	if len(q.User.Friends) != 2 || q.User.Friends[1].Name != "b" {
+		t.Errorf("got friends: %+v", q.User.Friends)
+	}
+	if header.Get("Content-Type") == "" {
+		t.Error("got empty response headers")
+	}
+}
+
+func TestClient_QueryIncremental_errorStatus(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/graphql-response+json")
+		w.WriteHeader(http.StatusBadRequest)
+		mustWrite(w, `{"errors": [{"message": "unknown directive @defer", "extensions": {"code": "GRAPHQL_VALIDATION_FAILED"}}]}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+
+	calls := 0
+	err := client.QueryIncremental(context.Background(), &q, nil, func(result graphql.IncrementalResult) error {
+		calls++
+
+		return nil
+	})
+
+	var errs graphql.Errors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Class() != graphql.ErrorClassValidation {
+		t.Fatalf("got error: %v, want: validation error", err)
+	}
+
+	var networkErr graphql.NetworkError
+	if !errors.As(err, &networkErr) || networkErr.StatusCode() != http.StatusBadRequest {
+		t.Errorf("got error: %v, want: 400 status", err)
+	}
+	if calls != 0 {
+		t.Errorf("got %d calls, want: 0", calls)
+	}
+}
+
+func TestClient_QueryIncremental_middlewareResponse(t *testing.T) {
+	client := graphql.NewClient("/graphql", nil, graphql.WithMiddleware(func(next graphql.Handler) graphql.Handler {
+		return func(ctx context.Context, req *graphql.Request) *graphql.Response {
+			return &graphql.Response{
+				Data: json.RawMessage(`{"user": {"name": "Gopher"}}`),
+			}
+		}
+	}))
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+
+	calls := 0
+	err := client.QueryIncremental(context.Background(), &q, nil, func(result graphql.IncrementalResult) error {
+		calls++
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 1 || q.User.Name != "Gopher" {
+		t.Errorf("got calls: %d, name: %q, want: 1, Gopher", calls, q.User.Name)
+	}
+}
+
+func TestClient_QueryIncremental_invalidIndex(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		writeIncrementalParts(t, w,
+			`{"data": {"friends": [{"name": "a"}]}, "hasNext": true}`,
+			`{"incremental": [{"items": [{"name": "b"}], "path": ["friends", -1]}], "hasNext": false}`,
+		)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Friends []struct {
+			Name string
+		} `graphql:"friends @stream(initialCount: 1)"`
+	}
+
+	err := client.QueryIncremental(context.Background(), &q, nil, nil)
+
+	var errs graphql.Errors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Code() != graphql.ErrGraphQLDecode {
+		t.Errorf("got error: %v, want: %s", err, graphql.ErrGraphQLDecode)
+	}
+}
+
+func TestClient_QueryIncremental_longStream(t *testing.T) {
+	const items = 100
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		parts := []string{`{"data": {"user": {"friends": []}}, "hasNext": true}`}
+		for i := 0; i < items; i++ {
+			parts = append(parts, fmt.Sprintf(
+				`{"incremental": [{"items": [{"name": "%d"}], "path": ["user", "friends", %d]}], "hasNext": %t}`,
+				i, i, i < items-1,
+			))
+		}
+		writeIncrementalParts(t, w, parts...)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		User *struct {
+			Friends []struct {
+				Name string
+			} `graphql:"friends @stream(initialCount: 0)"`
+		}
+	}
+
+	if err := client.QueryIncremental(context.Background(), &q, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if q.User == nil || len(q.User.Friends) != items || q.User.Friends[items-1].Name != fmt.Sprint(items-1) {
+		t.Errorf("got user: %+v", q.User)
+	}
+}
@@ -0,0 +1,120 @@
+package graphql
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// MetricsRecorder records metrics of GraphQL operations of the client.
+// The recorder is called concurrently by operations, so it must be safe for concurrent use.
+type MetricsRecorder interface {
+	// RecordOperation is called once when the operation completes,
+	// after the response is decoded.
+	RecordOperation(ctx context.Context, metrics OperationMetrics)
+}
+
+// OperationMetrics hold metrics of a GraphQL operation.
+type OperationMetrics struct {
+	OperationType OperationType
+	OperationName string
+	// Duration is the latency of the operation, including retries and decoding the response.
+	Duration time.Duration
+	// Attempts is the number of HTTP requests of the operation, including retries.
+	// It is 0 if the response doesn't come from the server, e.g. a cached response of middlewares.
+	// A batched request is counted as an attempt of every operation of the batch.
+	Attempts int
+	// StatusCode is the HTTP status of the last attempt, 0 if there is no HTTP response.
+	StatusCode int
+	// ErrorCodes are the extensions.code values of GraphQL errors, in order.
+	// The value is empty if the error doesn't have a code.
+	ErrorCodes []string
+}
+
+// WithMetricsRecorder creates an option to record metrics of every operation.
+func WithMetricsRecorder(recorder MetricsRecorder) ClientOption {
+	return func(c *Client) {
+		c.metricsRecorder = recorder
+	}
+}
+
+// operationMetricsKey is the context key of the operation metrics, so HTTP attempts can be recorded.
+type operationMetricsKey struct{}
+
+// operationMetrics collects metrics of the operation through the execution.
+// Attempts of a shared request may be recorded while the caller finishes, so the metrics are guarded by the mutex.
+type operationMetrics struct {
+	ctx      context.Context //nolint:containedctx
+	recorder MetricsRecorder
+	start    time.Time
+
+	mu      sync.Mutex
+	metrics OperationMetrics
+}
+
+// startMetrics starts collecting metrics of the operation if the recorder exists.
+func (c *Client) startMetrics(ctx context.Context, req *Request) (context.Context, *operationMetrics) {
+	if c.metricsRecorder == nil {
+		return ctx, nil
+	}
+
+	m := &operationMetrics{
+		recorder: c.metricsRecorder,
+		start:    time.Now(),
+		metrics: OperationMetrics{
+			OperationType: req.OperationType,
+			OperationName: req.OperationName,
+		},
+	}
+	m.ctx = context.WithValue(ctx, operationMetricsKey{}, m)
+
+	return m.ctx, m
+}
+
+// recordAttempt counts the HTTP attempt of the operation in the context if exists,
+// or of every caller of the shared request.
+func recordAttempt(ctx context.Context, resp *http.Response) {
+	if callers := requestCallers(ctx); callers != nil {
+		for _, caller := range callers {
+			recordAttempt(caller, resp)
+		}
+
+		return
+	}
+
+	m, ok := ctx.Value(operationMetricsKey{}).(*operationMetrics)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.metrics.Attempts++
+	m.metrics.StatusCode = 0
+
+	if resp != nil {
+		m.metrics.StatusCode = resp.StatusCode
+	}
+}
+
+// finish records metrics of the operation with the final errors.
+func (m *operationMetrics) finish(errs Errors) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	metrics := m.metrics
+	m.mu.Unlock()
+
+	metrics.Duration = time.Since(m.start)
+
+	for _, e := range errs {
+		code, _ := e.Extensions["code"].(string)
+		metrics.ErrorCodes = append(metrics.ErrorCodes, code)
+	}
+
+	m.recorder.RecordOperation(m.ctx, metrics)
+}
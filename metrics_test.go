@@ -0,0 +1,155 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+type metricsRecorderFunc func(ctx context.Context, metrics graphql.OperationMetrics)
+
+func (f metricsRecorderFunc) RecordOperation(ctx context.Context, metrics graphql.OperationMetrics) {
+	f(ctx, metrics)
+}
+
+func TestClientOption_WithMetricsRecorder(t *testing.T) {
+	var attempts int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": 1}}, "errors": [{"message": "forbidden", "extensions": {"code": "FORBIDDEN"}}, {"message": "no code"}]}`)
+	})
+
+	var mu sync.Mutex
+	var records []graphql.OperationMetrics
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRetry(1),
+		graphql.WithRetryBaseDelay(time.Millisecond),
+		graphql.WithMetricsRecorder(metricsRecorderFunc(func(ctx context.Context, metrics graphql.OperationMetrics) {
+			mu.Lock()
+			defer mu.Unlock()
+			records = append(records, metrics)
+		})),
+	)
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	if err := client.Query(context.Background(), &q, nil, graphql.OperationName("GetUser")); err == nil {
+		t.Fatal("got error: nil, want: forbidden")
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want: 1", len(records))
+	}
+	got := records[0]
+	if got.OperationType != graphql.OperationTypeQuery || got.OperationName != "GetUser" {
+		t.Errorf("got operation: %s %s, want: query GetUser", got.OperationType, got.OperationName)
+	}
+	if got.Attempts != 2 {
+		t.Errorf("got attempts: %d, want: 2", got.Attempts)
+	}
+	if got.StatusCode != http.StatusOK {
+		t.Errorf("got status code: %d, want: 200", got.StatusCode)
+	}
+	if got.Duration <= 0 {
+		t.Errorf("got duration: %s, want > 0", got.Duration)
+	}
+	// the decode error of processResponse is recorded too.
+	wantCodes := []string{"FORBIDDEN", "", graphql.ErrGraphQLDecode}
+	if !reflect.DeepEqual(got.ErrorCodes, wantCodes) {
+		t.Errorf("got error codes: %q, want: %q", got.ErrorCodes, wantCodes)
+	}
+}
+
+func TestClientOption_WithMetricsRecorder_raw(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"deleteUser": true}}`)
+	})
+
+	var records []graphql.OperationMetrics
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithMetricsRecorder(metricsRecorderFunc(func(ctx context.Context, metrics graphql.OperationMetrics) {
+			records = append(records, metrics)
+		})),
+	)
+
+	if _, err := client.ExecRaw(context.Background(), "mutation { deleteUser }", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want: 1", len(records))
+	}
+	if got := records[0]; got.OperationType != graphql.OperationTypeMutation || got.Attempts != 1 ||
+		got.StatusCode != http.StatusOK || len(got.ErrorCodes) != 0 {
+		t.Errorf("got metrics: %+v", got)
+	}
+}
+
+func TestClientOption_WithMetricsRecorder_batchWindow(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `[{"data": {"user": {"name": "Gopher"}}}, {"data": {"user": {"name": "Gopher"}}}]`)
+	})
+
+	var mu sync.Mutex
+	var records []graphql.OperationMetrics
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithBatchWindow(time.Second, 2),
+		graphql.WithMetricsRecorder(metricsRecorderFunc(func(ctx context.Context, metrics graphql.OperationMetrics) {
+			mu.Lock()
+			records = append(records, metrics)
+			mu.Unlock()
+		})),
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var q struct {
+				User struct {
+					Name string
+				}
+			}
+			if err := client.Query(context.Background(), &q, nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the batched request is counted as an attempt of every caller.
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want: 2", len(records))
+	}
+	for _, got := range records {
+		if got.Attempts != 1 || got.StatusCode != http.StatusOK {
+			t.Errorf("got metrics: %+v", got)
+		}
+	}
+}
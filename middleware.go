@@ -0,0 +1,150 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// OperationType represents the type of the GraphQL operation.
+type OperationType string
+
+const (
+	OperationTypeQuery        OperationType = "query"
+	OperationTypeMutation     OperationType = "mutation"
+	OperationTypeSubscription OperationType = "subscription"
+)
+
+// Request is the GraphQL operation which is passed through the middleware chain.
+// Middlewares can modify the request before calling the next handler.
+type Request struct {
+	OperationType OperationType
+	OperationName string
+	Query         string
+	Variables     map[string]any
+	// Extensions are sent in the extensions field of the request payload.
+	Extensions map[string]any
+	// Header is added to the HTTP request of the operation.
+	Header http.Header
+
+	op operationType
+	// the time to live of the result in the response cache, set by the CacheTTL option.
+	cacheTTL *time.Duration
+}
+
+// Response is the result of the GraphQL operation which is passed through the middleware chain.
+// Middlewares can modify the response, or return a new response without calling the next handler.
+type Response struct {
+	Data       json.RawMessage
+	Extensions json.RawMessage
+	Errors     Errors
+	// HTTPResponse is the response of the HTTP request.
+	// It is nil if the response doesn't come from the server, e.g. a cached response.
+	HTTPResponse *http.Response
+
+	raw *rawGraphQLResult
+}
+
+// Handler executes the GraphQL request and returns the response.
+type Handler func(ctx context.Context, req *Request) *Response
+
+// Middleware wraps the handler to intercept GraphQL operations of the client.
+type Middleware func(next Handler) Handler
+
+// WithMiddleware creates an option to add middlewares to the client.
+// Middlewares are executed in order, the first middleware is the outermost one.
+// The option can be used many times, new middlewares are appended to the chain.
+func WithMiddleware(middlewares ...Middleware) ClientOption {
+	return func(c *Client) {
+		c.middlewares = append(c.middlewares, middlewares...)
+	}
+}
+
+// newOperationRequest creates the middleware request of the operation.
+func newOperationRequest(
+	op operationType,
+	query string,
+	variables map[string]any,
+	options *constructOptionsOutput,
+) *Request {
+	req := &Request{
+		OperationType: op.exported(),
+		Query:         query,
+		Variables:     variables,
+		op:            op,
+	}
+
+	if options != nil {
+		req.OperationName = options.operationName
+		req.cacheTTL = options.cacheTTL
+		req.Header = options.requestHeader.Clone()
+	}
+
+	return req
+}
+
+// payload returns the request payload which is sent to the server.
+func (r *Request) payload() GraphQLRequestPayload {
+	return GraphQLRequestPayload{
+		Query:         r.Query,
+		Variables:     r.Variables,
+		OperationName: r.OperationName,
+		Extensions:    r.Extensions,
+	}
+}
+
+func newResponse(raw *rawGraphQLResult) *Response {
+	return &Response{
+		Data:         raw.Data,
+		Extensions:   raw.Extensions,
+		Errors:       raw.Errors,
+		HTTPResponse: raw.response,
+		raw:          raw,
+	}
+}
+
+// result converts the response to the internal result with changes of middlewares.
+func (r *Response) result() *rawGraphQLResult {
+	if r == nil {
+		return &rawGraphQLResult{
+			Errors: Errors{newError(ErrRequestError, errors.New("the middleware returned a nil response"))},
+		}
+	}
+
+	raw := r.raw
+	if raw == nil {
+		raw = &rawGraphQLResult{}
+	}
+
+	raw.Data = r.Data
+	raw.Extensions = r.Extensions
+	raw.Errors = r.Errors
+	raw.response = r.HTTPResponse
+
+	return raw
+}
+
+// handler returns the execution handler wrapped by middlewares.
+func (c *Client) handler() Handler {
+	handler := c.execute
+
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		handler = c.middlewares[i](handler)
+	}
+
+	return handler
+}
+
+// exported returns the public enum of the operation type.
+func (op operationType) exported() OperationType {
+	switch op {
+	case mutationOperation:
+		return OperationTypeMutation
+	case subscriptionOperation:
+		return OperationTypeSubscription
+	default:
+		return OperationTypeQuery
+	}
+}
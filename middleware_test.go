@@ -0,0 +1,126 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+func TestClientOption_WithMiddleware(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if got := req.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("got Authorization: %q, want: Bearer token", got)
+		}
+		var payload graphql.GraphQLRequestPayload
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := payload.Variables["id"], "2"; got != want {
+			t.Errorf("got id variable: %v, want: %s", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+
+	var calls []string
+	tracer := func(name string) graphql.Middleware {
+		return func(next graphql.Handler) graphql.Handler {
+			return func(ctx context.Context, req *graphql.Request) *graphql.Response {
+				calls = append(calls, name+" before "+string(req.OperationType)+" "+req.OperationName)
+				resp := next(ctx, req)
+				calls = append(calls, name+" after "+string(resp.Data))
+
+				return resp
+			}
+		}
+	}
+	auth := func(next graphql.Handler) graphql.Handler {
+		return func(ctx context.Context, req *graphql.Request) *graphql.Response {
+			req.Header = http.Header{"Authorization": []string{"Bearer token"}}
+			// mutate the variables before sending the request.
+			req.Variables["id"] = "2"
+
+			return next(ctx, req)
+		}
+	}
+
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithMiddleware(tracer("first")),
+		graphql.WithMiddleware(tracer("second"), auth),
+	)
+
+	var q struct {
+		User struct {
+			Name string
+		} `graphql:"user(id: $id)"`
+	}
+	err := client.Query(context.Background(), &q, map[string]any{"id": "1"}, graphql.OperationName("GetUser"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.User.Name != "Gopher" {
+		t.Errorf("got q.User.Name: %q, want: Gopher", q.User.Name)
+	}
+
+	want := []string{
+		"first before query GetUser",
+		"second before query GetUser",
+		`second after {"user": {"name": "Gopher"}}`,
+		`first after {"user": {"name": "Gopher"}}`,
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("got calls:\n%q\nwant:\n%q", calls, want)
+	}
+}
+
+func TestClientOption_WithMiddleware_shortCircuit(t *testing.T) {
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: errorRoundTripper{err: errors.New("unreachable")}},
+		graphql.WithMiddleware(func(next graphql.Handler) graphql.Handler {
+			return func(ctx context.Context, req *graphql.Request) *graphql.Response {
+				if req.OperationType == graphql.OperationTypeMutation {
+					return &graphql.Response{
+						Errors: graphql.Errors{{Message: "read only"}},
+					}
+				}
+
+				return &graphql.Response{
+					Data:       json.RawMessage(`{"user": {"name": "cached"}}`),
+					Extensions: json.RawMessage(`{"cached": true}`),
+				}
+			}
+		}),
+	)
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	var ext struct {
+		Cached bool `json:"cached"`
+	}
+	if err := client.Query(context.Background(), &q, nil, graphql.BindExtensions(&ext)); err != nil {
+		t.Fatal(err)
+	}
+	if q.User.Name != "cached" || !ext.Cached {
+		t.Errorf("got name: %q, cached: %t, want: cached, true", q.User.Name, ext.Cached)
+	}
+
+	var m struct {
+		DeleteUser bool
+	}
+	err := client.Mutate(context.Background(), &m, nil)
+	if err == nil || err.Error() != "Message: read only, Locations: [], Extensions: map[], Path: []" {
+		t.Errorf("got error: %v, want: read only", err)
+	}
+}
@@ -0,0 +1,533 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/hasura/go-graphql-client/ident"
+)
+
+// This file implements a normalized entity cache like the InMemoryCache of Apollo Client.
+// Response objects with an identity are stored once by the key, e.g. User:1,
+// and referenced by their parents. Root fields of queries are stored in the ROOT_QUERY entity.
+// The selection set is derived from the query struct, so a later query is answered
+// from the cache if every selected field is present.
+
+const rootQueryCacheKey = "ROOT_QUERY"
+
+// CacheKeyFunc returns the identity of the response object.
+// Objects without an identity are stored inline in their parents.
+type CacheKeyFunc func(object map[string]any) (string, bool)
+
+// DefaultCacheKey identifies objects by the __typename and id fields, e.g. User:1.
+// The query must select both fields to normalize the object.
+func DefaultCacheKey(object map[string]any) (string, bool) {
+	typename, ok := object["__typename"].(string)
+	if !ok || typename == "" {
+		return "", false
+	}
+
+	switch id := object["id"].(type) {
+	case string:
+		return typename + ":" + id, true
+	case json.Number:
+		return typename + ":" + id.String(), true
+	default:
+		return "", false
+	}
+}
+
+// cacheReference is the stored value of a normalized object.
+type cacheReference string
+
+// NormalizedCache stores response objects normalized by the cache key.
+// The cache is safe for concurrent use and can be shared between clients.
+type NormalizedCache struct {
+	keyFunc  CacheKeyFunc
+	mu       sync.RWMutex
+	entities map[string]map[string]any
+}
+
+// NewNormalizedCache creates a normalized cache.
+// If keyFunc is nil, DefaultCacheKey is used.
+func NewNormalizedCache(keyFunc CacheKeyFunc) *NormalizedCache {
+	if keyFunc == nil {
+		keyFunc = DefaultCacheKey
+	}
+
+	return &NormalizedCache{
+		keyFunc:  keyFunc,
+		entities: map[string]map[string]any{},
+	}
+}
+
+// WithNormalizedCache creates an option to answer Query calls from the normalized cache
+// when every selected field is cached. Results of Query and Mutate calls are written to the cache.
+// Cached results are returned without sending requests, middlewares aren't called either.
+// Pre-built queries of Exec methods aren't cached because the selection set is unknown.
+func WithNormalizedCache(cache *NormalizedCache) ClientOption {
+	return func(c *Client) {
+		c.normalizedCache = cache
+	}
+}
+
+// Entity returns a copy of the stored fields of the entity.
+// Field keys include arguments, e.g. user({"id":"1"}).
+func (nc *NormalizedCache) Entity(key string) (map[string]any, bool) {
+	nc.mu.RLock()
+	defer nc.mu.RUnlock()
+
+	entity, ok := nc.entities[key]
+	if !ok {
+		return nil, false
+	}
+
+	result := make(map[string]any, len(entity))
+	for k, v := range entity {
+		result[k] = v
+	}
+
+	return result, true
+}
+
+// Evict removes the entity from the cache. Queries which select the entity are sent to the server again.
+func (nc *NormalizedCache) Evict(key string) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	delete(nc.entities, key)
+}
+
+// Reset removes all entities from the cache.
+func (nc *NormalizedCache) Reset() {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	nc.entities = map[string]map[string]any{}
+}
+
+// read returns the response data of the query struct v if every selected field is cached.
+func (nc *NormalizedCache) read(v any, variables map[string]any) ([]byte, bool) {
+	selection, err := newCacheSelection(reflect.TypeOf(v))
+	if err != nil {
+		return nil, false
+	}
+
+	nc.mu.RLock()
+	defer nc.mu.RUnlock()
+
+	root, ok := nc.entities[rootQueryCacheKey]
+	if !ok {
+		return nil, false
+	}
+
+	result := map[string]any{}
+	if !nc.readSelection(selection, root, variables, result) {
+		return nil, false
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, false
+	}
+
+	return data, true
+}
+
+func (nc *NormalizedCache) readSelection(
+	selection *cacheSelection,
+	record map[string]any,
+	variables map[string]any,
+	result map[string]any,
+) bool {
+	for _, field := range selection.fields {
+		if field.inline {
+			if !field.matchType(record) {
+				continue
+			}
+
+			if !nc.readSelection(field.selection, record, variables, result) {
+				return false
+			}
+
+			continue
+		}
+
+		key, err := field.storageKey(variables)
+		if err != nil {
+			return false
+		}
+
+		value, ok := record[key]
+		if !ok {
+			return false
+		}
+
+		value, ok = nc.denormalize(field.selection, value, variables)
+		if !ok {
+			return false
+		}
+
+		result[field.responseKey] = value
+	}
+
+	return true
+}
+
+func (nc *NormalizedCache) denormalize(selection *cacheSelection, value any, variables map[string]any) (any, bool) {
+	if selection == nil {
+		return value, true
+	}
+
+	switch v := value.(type) {
+	case cacheReference:
+		record, ok := nc.entities[string(v)]
+		if !ok {
+			return nil, false
+		}
+
+		result := map[string]any{}
+
+		return result, nc.readSelection(selection, record, variables, result)
+	case map[string]any:
+		result := map[string]any{}
+
+		return result, nc.readSelection(selection, v, variables, result)
+	case []any:
+		results := make([]any, len(v))
+
+		for i, item := range v {
+			result, ok := nc.denormalize(selection, item, variables)
+			if !ok {
+				return nil, false
+			}
+
+			results[i] = result
+		}
+
+		return results, true
+	default:
+		return value, true
+	}
+}
+
+// write normalizes the response data of the query struct v into the cache.
+// Root fields of mutations aren't stored, only entities are updated.
+func (nc *NormalizedCache) write(op operationType, v any, variables map[string]any, data []byte) {
+	selection, err := newCacheSelection(reflect.TypeOf(v))
+	if err != nil {
+		return
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var object map[string]any
+	if err := decoder.Decode(&object); err != nil {
+		return
+	}
+
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	root := map[string]any{}
+	if op == queryOperation {
+		root = nc.entity(rootQueryCacheKey)
+	}
+
+	nc.writeSelection(selection, object, variables, root)
+}
+
+// entity returns the stored entity, creates it if not exists.
+func (nc *NormalizedCache) entity(key string) map[string]any {
+	record, ok := nc.entities[key]
+	if !ok {
+		record = map[string]any{}
+		nc.entities[key] = record
+	}
+
+	return record
+}
+
+func (nc *NormalizedCache) writeSelection(
+	selection *cacheSelection,
+	object map[string]any,
+	variables map[string]any,
+	record map[string]any,
+) {
+	for _, field := range selection.fields {
+		if field.inline {
+			if field.matchType(object) {
+				nc.writeSelection(field.selection, object, variables, record)
+			}
+
+			continue
+		}
+
+		value, ok := object[field.responseKey]
+		if !ok {
+			continue
+		}
+
+		key, err := field.storageKey(variables)
+		if err != nil {
+			continue
+		}
+
+		record[key] = nc.normalize(field.selection, value, variables)
+	}
+}
+
+func (nc *NormalizedCache) normalize(selection *cacheSelection, value any, variables map[string]any) any {
+	if selection == nil {
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		if key, ok := nc.keyFunc(v); ok {
+			nc.writeSelection(selection, v, variables, nc.entity(key))
+
+			return cacheReference(key)
+		}
+
+		record := map[string]any{}
+		nc.writeSelection(selection, v, variables, record)
+
+		return record
+	case []any:
+		results := make([]any, len(v))
+		for i, item := range v {
+			results[i] = nc.normalize(selection, item, variables)
+		}
+
+		return results
+	default:
+		return value
+	}
+}
+
+// cacheSelection is the selection set of a query struct.
+type cacheSelection struct {
+	fields []cacheField
+}
+
+type cacheField struct {
+	// the field name in the response, the alias if exists.
+	responseKey string
+	name        string
+	arguments   string
+	// inline fields are embedded structs and inline fragments,
+	// whose fields are merged into the parent object.
+	inline        bool
+	typeCondition string
+	// the selection set of the object field, nil if the field is a scalar.
+	selection *cacheSelection
+}
+
+// newCacheSelection creates the selection set of the struct type,
+// following the rules of writeQuery.
+func newCacheSelection(t reflect.Type) (*cacheSelection, error) {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Array {
+			return nil, fmt.Errorf("type %v is not supported by the normalized cache", t)
+		}
+
+		t = t.Elem()
+	}
+
+	switch {
+	case t.Kind() == reflect.Map:
+		return nil, fmt.Errorf("type %v is not supported by the normalized cache", t)
+	case t.Kind() != reflect.Struct,
+		reflect.PointerTo(t).Implements(jsonUnmarshaler),
+		t.AssignableTo(idType):
+		return nil, nil
+	}
+
+	selection := &cacheSelection{}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		value, ok := f.Tag.Lookup("graphql")
+		if value == "-" {
+			continue
+		}
+
+		var field cacheField
+
+		switch {
+		case f.Anonymous && !ok:
+			field.inline = true
+		case !ok:
+			field.name = ident.ParseMixedCaps(f.Name).ToLowerCamelCase()
+			field.responseKey = field.name
+		case strings.HasPrefix(strings.TrimSpace(value), "..."):
+			field.inline = true
+			field.typeCondition = parseTypeCondition(value)
+		default:
+			field.responseKey, field.name, field.arguments = parseCacheField(value)
+		}
+
+		if !isTrue(f.Tag.Get("scalar")) {
+			fieldSelection, err := newCacheSelection(f.Type)
+			if err != nil {
+				return nil, err
+			}
+
+			field.selection = fieldSelection
+		}
+
+		if field.inline && field.selection == nil {
+			continue
+		}
+
+		selection.fields = append(selection.fields, field)
+	}
+
+	return selection, nil
+}
+
+// matchType checks if the inline fragment applies to the object.
+// If the object doesn't have the __typename field, the fragment is assumed to match.
+func (f cacheField) matchType(object map[string]any) bool {
+	if f.typeCondition == "" {
+		return true
+	}
+
+	typename, ok := object["__typename"].(string)
+
+	return !ok || typename == f.typeCondition
+}
+
+// storageKey returns the key of the field in the entity with resolved arguments,
+// e.g. user(id: $id) is stored as user(id:"1").
+func (f cacheField) storageKey(variables map[string]any) (string, error) {
+	if f.arguments == "" {
+		return f.name, nil
+	}
+
+	arguments, err := normalizeCacheArguments(f.arguments, variables)
+	if err != nil {
+		return "", err
+	}
+
+	return f.name + arguments, nil
+}
+
+// parseTypeCondition returns the type of the inline fragment, e.g. User of `... on User`.
+func parseTypeCondition(tag string) string {
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(tag), "..."))
+	if len(fields) >= 2 && fields[0] == "on" {
+		return fields[1]
+	}
+
+	return ""
+}
+
+// parseCacheField parses the graphql tag of the field, e.g. `alias: user(id: $id) @include(if: $x)`.
+// Directives are ignored, arguments of the field are the parentheses before the first directive.
+func parseCacheField(tag string) (responseKey string, name string, arguments string) {
+	head := strings.TrimSpace(tag)
+
+	if start := strings.IndexAny(head, "(@"); start >= 0 {
+		if head[start] == '(' {
+			end := findClosingParen(head, start)
+			arguments = head[start:end]
+		}
+
+		head = head[:start]
+	}
+
+	name = strings.TrimSpace(head)
+	responseKey = name
+
+	if alias, field, ok := strings.Cut(head, ":"); ok {
+		responseKey = strings.TrimSpace(alias)
+		name = strings.TrimSpace(field)
+	}
+
+	return responseKey, name, arguments
+}
+
+// findClosingParen returns the index after the parenthesis which closes the one at start.
+func findClosingParen(s string, start int) int {
+	depth := 0
+
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			i = skipStringValue(s, i)
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+
+	return len(s)
+}
+
+// normalizeCacheArguments removes insignificant whitespaces and commas,
+// and replaces variables with their JSON values.
+func normalizeCacheArguments(arguments string, variables map[string]any) (string, error) {
+	var b strings.Builder
+
+	var last byte
+
+	separated := false
+	punctuator := func(ch byte) bool {
+		return strings.IndexByte("():[]{}", ch) >= 0
+	}
+	write := func(s string) {
+		if separated && last != 0 && !punctuator(last) && !punctuator(s[0]) {
+			_ = b.WriteByte(' ')
+		}
+
+		separated = false
+		last = s[len(s)-1]
+		_, _ = b.WriteString(s)
+	}
+
+	for i := 0; i < len(arguments); i++ {
+		ch := arguments[i]
+
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == ',':
+			separated = true
+		case ch == '"':
+			end := skipStringValue(arguments, i)
+			if end < len(arguments) {
+				end++
+			}
+
+			write(arguments[i:end])
+			i = end - 1
+		case ch == '$':
+			end := i + 1
+			for end < len(arguments) && isNameContinue(arguments[end]) {
+				end++
+			}
+
+			value, err := json.Marshal(variables[arguments[i+1:end]])
+			if err != nil {
+				return "", err
+			}
+
+			write(string(value))
+			i = end - 1
+		default:
+			write(string(ch))
+		}
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,242 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+func newNormalizedCacheTestClient(t *testing.T, requests *[]string) *graphql.Client {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var payload graphql.GraphQLRequestPayload
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			t.Fatal(err)
+		}
+		*requests = append(*requests, payload.Query)
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case strings.HasPrefix(payload.Query, "mutation"):
+			mustWrite(w, `{"data": {"updateUser": {"__typename": "User", "id": "1", "name": "Gopher 2"}}}`)
+		case strings.Contains(payload.Query, "users"):
+			mustWrite(w, `{"data": {"users": [
+				{"__typename": "User", "id": "1", "name": "Gopher", "pet": {"kind": "cat"}},
+				{"__typename": "User", "id": "2", "name": "Gordon", "pet": null}
+			]}}`)
+		case strings.Contains(payload.Query, "email"):
+			mustWrite(w, `{"data": {"user": {"name": "Gopher", "email": "gopher@example.com"}}}`)
+		default:
+			mustWrite(w, `{"data": {"user": {"__typename": "User", "id": "1", "name": "Gopher"}}}`)
+		}
+	})
+
+	return graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithNormalizedCache(graphql.NewNormalizedCache(nil)),
+	)
+}
+
+func TestClientOption_WithNormalizedCache(t *testing.T) {
+	var requests []string
+	client := newNormalizedCacheTestClient(t, &requests)
+
+	var q struct {
+		User struct {
+			Typename string `graphql:"__typename"`
+			ID       string
+			Name     string
+		} `graphql:"user(id: $id)"`
+	}
+	variables := map[string]any{"id": graphql.ID("1")}
+	if err := client.Query(context.Background(), &q, variables); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want: 1", len(requests))
+	}
+
+	// a subset of cached fields with an alias and different formatting of arguments.
+	var q2 struct {
+		Gopher struct {
+			Name string
+		} `graphql:"gopher: user(id:$id)"`
+	}
+	if err := client.Query(context.Background(), &q2, variables); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 {
+		t.Errorf("got %d requests, want: 1", len(requests))
+	}
+	if q2.Gopher.Name != "Gopher" {
+		t.Errorf("got name: %q, want: Gopher", q2.Gopher.Name)
+	}
+
+	// the email field isn't cached yet.
+	var q3 struct {
+		User struct {
+			Name  string
+			Email string
+		} `graphql:"user(id: $id)"`
+	}
+	if err := client.Query(context.Background(), &q3, variables); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 {
+		t.Errorf("got %d requests, want: 2", len(requests))
+	}
+
+	// other arguments aren't cached.
+	if err := client.Query(context.Background(), &q, map[string]any{"id": graphql.ID("2")}); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 3 {
+		t.Errorf("got %d requests, want: 3", len(requests))
+	}
+}
+
+func TestClientOption_WithNormalizedCache_directives(t *testing.T) {
+	var requests []string
+	client := newNormalizedCacheTestClient(t, &requests)
+
+	var q struct {
+		User struct {
+			Typename string `graphql:"__typename"`
+			ID       string
+			Name     string
+		} `graphql:"user @skip(if: $skip)"`
+	}
+	if err := client.Query(context.Background(), &q, map[string]any{"skip": false}); err != nil {
+		t.Fatal(err)
+	}
+
+	// arguments of directives aren't arguments of the field.
+	var q2 struct {
+		User struct {
+			Name string
+		} `graphql:"user @include(if: $include)"`
+	}
+	if err := client.Query(context.Background(), &q2, map[string]any{"include": true}); err != nil {
+		t.Fatal(err)
+	}
+
+	var q3 struct {
+		User struct {
+			Name string
+		}
+	}
+	if err := client.Query(context.Background(), &q3, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 1 {
+		t.Errorf("got %d requests, want: 1", len(requests))
+	}
+	if q2.User.Name != "Gopher" || q3.User.Name != "Gopher" {
+		t.Errorf("got names: %q, %q, want: Gopher", q2.User.Name, q3.User.Name)
+	}
+}
+
+func TestClientOption_WithNormalizedCache_mutation(t *testing.T) {
+	var requests []string
+	client := newNormalizedCacheTestClient(t, &requests)
+
+	type user struct {
+		Typename string `graphql:"__typename"`
+		ID       string
+		Name     string
+		Pet      *struct {
+			Kind string
+		}
+	}
+	var q struct {
+		Users []user
+	}
+	if err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var m struct {
+		UpdateUser struct {
+			Typename string `graphql:"__typename"`
+			ID       string
+			Name     string
+		} `graphql:"updateUser(id: \"1\", name: \"Gopher 2\")"`
+	}
+	if err := client.Mutate(context.Background(), &m, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the list is answered from the cache, with the entity updated by the mutation.
+	var q2 struct {
+		Users []user
+	}
+	if err := client.Query(context.Background(), &q2, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 {
+		t.Errorf("got %d requests, want: 2", len(requests))
+	}
+	if len(q2.Users) != 2 || q2.Users[0].Name != "Gopher 2" || q2.Users[1].Name != "Gordon" {
+		t.Fatalf("got users: %+v", q2.Users)
+	}
+	if q2.Users[0].Pet == nil || q2.Users[0].Pet.Kind != "cat" || q2.Users[1].Pet != nil {
+		t.Errorf("got pets: %+v, %+v", q2.Users[0].Pet, q2.Users[1].Pet)
+	}
+}
+
+func TestNormalizedCache_Evict(t *testing.T) {
+	cache := graphql.NewNormalizedCache(func(object map[string]any) (string, bool) {
+		id, ok := object["id"].(string)
+
+		return "Node:" + id, ok
+	})
+
+	requests := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		_, _ = io.Copy(io.Discard, req.Body)
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"node": {"id": "1", "name": "Gopher"}}}`)
+	})
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithNormalizedCache(cache),
+	)
+
+	var q struct {
+		Node struct {
+			ID   string
+			Name string
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := client.Query(context.Background(), &q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want: 1", requests)
+	}
+
+	entity, ok := cache.Entity("Node:1")
+	if !ok || entity["name"] != "Gopher" {
+		t.Errorf("got entity: %v, want the cached node", entity)
+	}
+
+	cache.Evict("Node:1")
+	if err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Errorf("got %d requests, want: 2", requests)
+	}
+}
@@ -9,37 +9,89 @@ import (
 type ClientOption func(c *Client)
 
 // WithRetry creates an option to indicate the number of retries.
+//
+// Deprecated: use WithRetryPolicy with NewBackoffRetryPolicy instead.
 func WithRetry(maxRetries int) ClientOption {
 	return func(c *Client) {
-		c.maxRetries = maxRetries
+		c.updateBackoffRetryPolicy(func(policy *BackoffRetryPolicy) {
+			policy.MaxRetries = maxRetries
+		})
 	}
 }
 
 // WithRetryBaseDelay creates an option to indicate the base delay factor of retries.
+//
+// Deprecated: use WithRetryPolicy with NewBackoffRetryPolicy instead.
 func WithRetryBaseDelay(delay time.Duration) ClientOption {
 	return func(c *Client) {
-		c.retryBaseDelay = delay
+		c.updateBackoffRetryPolicy(func(policy *BackoffRetryPolicy) {
+			policy.BaseDelay = delay
+		})
 	}
 }
 
 // WithRetryExponentialRate creates an option to indicate the exponential rate of retries.
+//
+// Deprecated: use WithRetryPolicy with NewBackoffRetryPolicy instead.
 func WithRetryExponentialRate(rate float64) ClientOption {
 	return func(c *Client) {
-		c.retryExponentialRate = rate
+		c.updateBackoffRetryPolicy(func(policy *BackoffRetryPolicy) {
+			policy.ExponentialRate = rate
+		})
 	}
 }
 
 // WithRetryHTTPStatus creates an option to retry if the HTTP response status is in the status slice.
+//
+// Deprecated: use WithRetryPolicy with NewBackoffRetryPolicy instead.
 func WithRetryHTTPStatus(status []int) ClientOption {
 	return func(c *Client) {
-		c.retryHttpStatus = status
+		c.updateBackoffRetryPolicy(func(policy *BackoffRetryPolicy) {
+			policy.HTTPStatus = status
+		})
 	}
 }
 
 // WithRetryOnGraphQLError creates a callback option to check if the graphql error is retryable.
+//
+// Deprecated: use WithRetryPolicy with NewBackoffRetryPolicy instead.
 func WithRetryOnGraphQLError(callback func(errs Errors) bool) ClientOption {
 	return func(c *Client) {
-		c.retryOnGraphQLError = callback
+		c.updateBackoffRetryPolicy(func(policy *BackoffRetryPolicy) {
+			policy.RetryOnGraphQLError = callback
+		})
+	}
+}
+
+// WithAutomaticPersistedQueries creates an option to send the SHA-256 hash of the query instead of the full document.
+// The full query is sent again if the server replies with PersistedQueryNotFound.
+func WithAutomaticPersistedQueries(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.persistedQueries = enabled
+	}
+}
+
+// WithHTTPGet creates an option to send query operations with the HTTP GET method, so responses can be cached by CDNs.
+// The request falls back to POST if the URL is longer than maxURLLength. The default max length is 2048 if the value is zero.
+// Mutations are always sent with POST.
+func WithHTTPGet(maxURLLength int) ClientOption {
+	return func(c *Client) {
+		c.useHTTPGet = true
+
+		if maxURLLength > 0 {
+			c.maxGetURLLength = maxURLLength
+		}
+	}
+}
+
+// WithBatchWindow creates an option to collect query operations which are issued within the window,
+// and send them in a single batched request. The batch is sent early if it reaches maxBatchSize.
+// The max batch size is unlimited if the value is zero. Batched queries are always sent with the full query document.
+// Queries aren't batched if they are sent with the GET method.
+func WithBatchWindow(window time.Duration, maxBatchSize int) ClientOption {
+	return func(c *Client) {
+		c.batchWindow = window
+		c.maxBatchSize = maxBatchSize
 	}
 }
 
@@ -102,3 +154,19 @@ func (ono bindResponseHeadersOption) Type() OptionType {
 func BindResponseHeaders(value *http.Header) Option {
 	return bindResponseHeadersOption{value: value}
 }
+
+// cacheTTLOption sets the time to live of the operation result in the response cache.
+type cacheTTLOption struct {
+	ttl time.Duration
+}
+
+func (ono cacheTTLOption) Type() OptionType {
+	return "cache_ttl"
+}
+
+// CacheTTL sets the time to live of the query result in the response cache,
+// which overrides the Cache-Control header and the default TTL of the cache.
+// The result isn't cached if the value is zero or negative.
+func CacheTTL(ttl time.Duration) Option {
+	return cacheTTLOption{ttl: ttl}
+}
@@ -0,0 +1,88 @@
+package graphql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// Automatic persisted queries (APQ) send the SHA-256 hash of the query instead of the full document.
+// The full query is only sent when the server hasn't registered the hash yet.
+// https://www.apollographql.com/docs/apollo-server/performance/apq
+
+const (
+	// ErrPersistedQueryNotFound is the error code returned by the server if the persisted query hash is unknown.
+	ErrPersistedQueryNotFound = "PERSISTED_QUERY_NOT_FOUND"
+	// ErrPersistedQueryNotSupported is the error code returned by the server if it doesn't support persisted queries.
+	ErrPersistedQueryNotSupported = "PERSISTED_QUERY_NOT_SUPPORTED"
+
+	persistedQueryNotFoundMessage     = "PersistedQueryNotFound"
+	persistedQueryNotSupportedMessage = "PersistedQueryNotSupported"
+)
+
+// newPersistedQueryPayload returns a copy of the payload which only contains the hash of the query.
+func newPersistedQueryPayload(payload GraphQLRequestPayload) GraphQLRequestPayload {
+	hash := sha256.Sum256([]byte(payload.Query))
+	extensions := make(map[string]any, len(payload.Extensions)+1)
+
+	for key, value := range payload.Extensions {
+		extensions[key] = value
+	}
+
+	extensions["persistedQuery"] = map[string]any{
+		"version":    1,
+		"sha256Hash": hex.EncodeToString(hash[:]),
+	}
+
+	return GraphQLRequestPayload{
+		Variables:     payload.Variables,
+		OperationName: payload.OperationName,
+		Extensions:    extensions,
+	}
+}
+
+// isPersistedQueryNotFound checks if the server asks the client to resend the full query.
+func isPersistedQueryNotFound(errs Errors) bool {
+	for _, e := range errs {
+		switch e.Message {
+		case persistedQueryNotFoundMessage, persistedQueryNotSupportedMessage:
+			return true
+		}
+
+		if code, ok := e.Extensions["code"].(string); ok &&
+			(code == ErrPersistedQueryNotFound || code == ErrPersistedQueryNotSupported) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// retryPersistedQuery resends the subscription with the full query and its hash
+// if the server doesn't recognize the hash of the persisted query.
+func (sc *SubscriptionContext) retryPersistedQuery(sub Subscription, errs Errors) bool {
+	if !sub.persistedQuery || sub.persistedQueryNotFound || !isPersistedQueryNotFound(errs) {
+		return false
+	}
+
+	// the retry is sent under a new ID, because the server may still complete
+	// the first attempt and reject a reused ID.
+	retry := sub.Clone()
+	retry.persistedQueryNotFound = true
+
+	if err := sc.client.protocol.Subscribe(sc, retry); err != nil {
+		sc.Log(
+			fmt.Sprintf("failed to resend the persisted query: %s; id: %s", err, retry.id),
+			map[string]any{
+				"source": "client",
+			},
+			GQLInternal,
+		)
+
+		if retry.handler != nil {
+			retry.handler(nil, err)
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,321 @@
+package graphql_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/hasura/go-graphql-client"
+)
+
+type persistedQueryRequest struct {
+	Query      string `json:"query"`
+	Extensions struct {
+		PersistedQuery struct {
+			Version    int    `json:"version"`
+			Sha256Hash string `json:"sha256Hash"`
+		} `json:"persistedQuery"`
+	} `json:"extensions"`
+}
+
+func sha256Hex(s string) string {
+	hash := sha256.Sum256([]byte(s))
+
+	return hex.EncodeToString(hash[:])
+}
+
+func TestClient_Query_automaticPersistedQueries(t *testing.T) {
+	var requests []persistedQueryRequest
+	registered := map[string]bool{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var body persistedQueryRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		requests = append(requests, body)
+
+		hash := body.Extensions.PersistedQuery.Sha256Hash
+		w.Header().Set("Content-Type", "application/json")
+		if body.Query == "" && !registered[hash] {
+			mustWrite(w, `{"errors": [{"message": "PersistedQueryNotFound", "extensions": {"code": "PERSISTED_QUERY_NOT_FOUND"}}]}`)
+			return
+		}
+		if body.Query != "" {
+			if got := sha256Hex(body.Query); got != hash {
+				t.Errorf("got hash: %s, want: %s", hash, got)
+			}
+			registered[hash] = true
+		}
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithAutomaticPersistedQueries(true),
+	)
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		q.User.Name = ""
+		if err := client.Query(context.Background(), &q, nil); err != nil {
+			t.Fatal(err)
+		}
+		if q.User.Name != "Gopher" {
+			t.Errorf("got q.User.Name: %q, want: Gopher", q.User.Name)
+		}
+	}
+
+	// the first query is sent twice: hash only and the full query, the second query is sent with the hash only.
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want: 3", len(requests))
+	}
+	wantHash := sha256Hex("{user{name}}")
+	for i, req := range requests {
+		if req.Extensions.PersistedQuery.Sha256Hash != wantHash {
+			t.Errorf("requests[%d]: got hash %s, want: %s", i, req.Extensions.PersistedQuery.Sha256Hash, wantHash)
+		}
+		if req.Extensions.PersistedQuery.Version != 1 {
+			t.Errorf("requests[%d]: got version %d, want: 1", i, req.Extensions.PersistedQuery.Version)
+		}
+	}
+	if requests[0].Query != "" || requests[1].Query != "{user{name}}" || requests[2].Query != "" {
+		t.Errorf("got unexpected queries: %q, %q, %q", requests[0].Query, requests[1].Query, requests[2].Query)
+	}
+}
+
+func TestSubscription_automaticPersistedQueries(t *testing.T) {
+	var mu sync.Mutex
+	var subscribePayloads []persistedQueryRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+			Subprotocols: []string{"graphql-transport-ws"},
+		})
+		if err != nil {
+			return
+		}
+		defer conn.CloseNow()
+
+		ctx := r.Context()
+		for {
+			var msg graphql.OperationMessage
+			if err := wsjson.Read(ctx, conn, &msg); err != nil {
+				return
+			}
+
+			switch msg.Type {
+			case graphql.GQLConnectionInit:
+				_ = wsjson.Write(ctx, conn, graphql.OperationMessage{Type: graphql.GQLConnectionAck})
+			case graphql.GQLSubscribe:
+				var payload persistedQueryRequest
+				if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				subscribePayloads = append(subscribePayloads, payload)
+				mu.Unlock()
+
+				if payload.Query == "" {
+					_ = wsjson.Write(ctx, conn, graphql.OperationMessage{
+						ID:      msg.ID,
+						Type:    graphql.GQLError,
+						Payload: json.RawMessage(`[{"message": "PersistedQueryNotFound"}]`),
+					})
+					continue
+				}
+				_ = wsjson.Write(ctx, conn, graphql.OperationMessage{
+					ID:      msg.ID,
+					Type:    graphql.GQLNext,
+					Payload: json.RawMessage(`{"data": {"helloSaid": {"msg": "hello"}}}`),
+				})
+				_ = wsjson.Write(ctx, conn, graphql.OperationMessage{ID: msg.ID, Type: graphql.GQLComplete})
+			}
+		}
+	}))
+	defer server.Close()
+
+	client := graphql.NewSubscriptionClient(strings.Replace(server.URL, "http", "ws", 1)).
+		WithProtocol(graphql.GraphQLWS).
+		WithAutomaticPersistedQueries(true).
+		WithSyncMode(true)
+
+	var sub struct {
+		HelloSaid struct {
+			Msg string
+		}
+	}
+
+	msgChan := make(chan string, 1)
+	_, err := client.Subscribe(&sub, nil, func(data []byte, err error) error {
+		if err != nil {
+			t.Errorf("got error: %s, want: nil", err)
+			return nil
+		}
+		if err := graphql.UnmarshalGraphQL(data, &sub); err != nil {
+			t.Error(err)
+		}
+		msgChan <- sub.HelloSaid.Msg
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		if err := client.Run(); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer client.Close()
+
+	select {
+	case msg := <-msgChan:
+		if msg != "hello" {
+			t.Errorf("got message: %s, want: hello", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription timeout")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// the full query is resent with the hash, so the server can register it.
+	const query = "subscription{helloSaid{msg}}"
+	if len(subscribePayloads) != 2 || subscribePayloads[0].Query != "" || subscribePayloads[1].Query != query {
+		t.Fatalf("got unexpected subscribe payloads: %+v", subscribePayloads)
+	}
+	for i, payload := range subscribePayloads {
+		if payload.Extensions.PersistedQuery.Version != 1 || payload.Extensions.PersistedQuery.Sha256Hash != sha256Hex(query) {
+			t.Errorf("got persisted query of payload %d: %+v, want the hash of %q", i, payload.Extensions.PersistedQuery, query)
+		}
+	}
+}
+
+func TestSubscription_automaticPersistedQueries_next(t *testing.T) {
+	var mu sync.Mutex
+	var subscribeIDs []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+			Subprotocols: []string{"graphql-transport-ws"},
+		})
+		if err != nil {
+			return
+		}
+		defer conn.CloseNow()
+
+		ctx := r.Context()
+		seen := map[string]bool{}
+		for {
+			var msg graphql.OperationMessage
+			if err := wsjson.Read(ctx, conn, &msg); err != nil {
+				return
+			}
+
+			switch msg.Type {
+			case graphql.GQLConnectionInit:
+				_ = wsjson.Write(ctx, conn, graphql.OperationMessage{Type: graphql.GQLConnectionAck})
+			case graphql.GQLSubscribe:
+				var payload persistedQueryRequest
+				if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				subscribeIDs = append(subscribeIDs, msg.ID)
+				mu.Unlock()
+
+				if seen[msg.ID] {
+					_ = conn.Close(4409, "Subscriber for "+msg.ID+" already exists")
+					return
+				}
+				seen[msg.ID] = true
+
+				// the server reports the unknown hash in the execution result,
+				// and completes the operation.
+				if payload.Query == "" {
+					_ = wsjson.Write(ctx, conn, graphql.OperationMessage{
+						ID:      msg.ID,
+						Type:    graphql.GQLNext,
+						Payload: json.RawMessage(`{"errors": [{"message": "PersistedQueryNotFound"}]}`),
+					})
+					_ = wsjson.Write(ctx, conn, graphql.OperationMessage{ID: msg.ID, Type: graphql.GQLComplete})
+					continue
+				}
+				_ = wsjson.Write(ctx, conn, graphql.OperationMessage{
+					ID:      msg.ID,
+					Type:    graphql.GQLNext,
+					Payload: json.RawMessage(`{"data": {"helloSaid": {"msg": "hello"}}}`),
+				})
+			}
+		}
+	}))
+	defer server.Close()
+
+	client := graphql.NewSubscriptionClient(strings.Replace(server.URL, "http", "ws", 1)).
+		WithProtocol(graphql.GraphQLWS).
+		WithAutomaticPersistedQueries(true).
+		WithSyncMode(true)
+
+	var sub struct {
+		HelloSaid struct {
+			Msg string
+		}
+	}
+
+	msgChan := make(chan string, 1)
+	_, err := client.Subscribe(&sub, nil, func(data []byte, err error) error {
+		if err != nil {
+			t.Errorf("got error: %s, want: nil", err)
+			return nil
+		}
+		if err := graphql.UnmarshalGraphQL(data, &sub); err != nil {
+			t.Error(err)
+		}
+		msgChan <- sub.HelloSaid.Msg
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		if err := client.Run(); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer client.Close()
+
+	select {
+	case msg := <-msgChan:
+		if msg != "hello" {
+			t.Errorf("got message: %s, want: hello", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription timeout")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// the complete message of the first attempt doesn't stop the resent subscription.
+	if len(subscribeIDs) != 2 || subscribeIDs[0] == subscribeIDs[1] {
+		t.Fatalf("got subscribe ids: %v, want 2 different ids", subscribeIDs)
+	}
+}
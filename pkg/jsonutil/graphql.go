@@ -38,6 +38,15 @@ func UnmarshalGraphQL(data []byte, v any) error {
 	}
 }
 
+// DecodeGraphQL decodes the next JSON value from the decoder and stores
+// the result in the GraphQL query data structure pointed to by v.
+// The decoder is left after the value, so the rest of the stream can be decoded,
+// e.g. the data of the response body is decoded in one pass.
+// The decoder should use numbers, see json.Decoder.UseNumber.
+func DecodeGraphQL(dec *json.Decoder, v any) error {
+	return (&decoder{tokenizer: dec}).Decode(v)
+}
+
 // decoder is a JSON decoder that performs custom unmarshaling behavior
 // for GraphQL query data structures. It's implemented on top of a JSON tokenizer.
 type decoder struct {
@@ -636,3 +645,118 @@ func unmarshalValue(value any, v reflect.Value) error {
 
 	return nil
 }
+
+// ResolvePath resolves the response path of a GraphQL error, e.g. ["user", "orders", 2, "total"],
+// to the values of the decoded GraphQL query data structure pointed to by v.
+// Response keys are matched with the same rules as the decoder, including fragments and embedded structs.
+// It returns the addressable value of every resolved prefix of the path in order,
+// and stops at nil pointers, missing fields and indexes out of range.
+func ResolvePath(v any, path []any) []reflect.Value {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil
+	}
+
+	current := rv.Elem()
+	values := make([]reflect.Value, 0, len(path))
+
+	for _, segment := range path {
+		for current.Kind() == reflect.Ptr {
+			if current.IsNil() {
+				return values
+			}
+
+			current = current.Elem()
+		}
+
+		var next reflect.Value
+
+		switch current.Kind() {
+		case reflect.Struct:
+			key, ok := segment.(string)
+			if !ok {
+				return values
+			}
+
+			next = resolveField(current, key)
+		case reflect.Slice, reflect.Array:
+			index, ok := PathIndex(segment)
+			if !ok || index < 0 || index >= current.Len() {
+				return values
+			}
+
+			next = current.Index(index)
+		default:
+		}
+
+		if !next.IsValid() || !next.CanAddr() {
+			return values
+		}
+
+		values = append(values, next)
+		current = next
+	}
+
+	return values
+}
+
+// resolveField finds the struct field of the response key, in fragments and embedded structs too.
+// If the key matches fields of many fragments, e.g. union types, the first non-zero field is preferred.
+func resolveField(v reflect.Value, key string) reflect.Value {
+	if f, _ := fieldByGraphQLName(v, key); f.IsValid() {
+		return f
+	}
+
+	var match reflect.Value
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" || (!isGraphQLFragment(field) && !field.Anonymous) {
+			continue
+		}
+
+		fragment := v.Field(i)
+		for fragment.Kind() == reflect.Ptr {
+			if fragment.IsNil() {
+				break
+			}
+
+			fragment = fragment.Elem()
+		}
+
+		if fragment.Kind() != reflect.Struct {
+			continue
+		}
+
+		f := resolveField(fragment, key)
+		if !f.IsValid() {
+			continue
+		}
+
+		if !f.IsZero() {
+			return f
+		}
+
+		if !match.IsValid() {
+			match = f
+		}
+	}
+
+	return match
+}
+
+// PathIndex converts the list index of the response path, which is decoded as a JSON number.
+func PathIndex(segment any) (int, bool) {
+	switch index := segment.(type) {
+	case int:
+		return index, true
+	case float64:
+		return int(index), index == float64(int(index))
+	case json.Number:
+		i, err := index.Int64()
+
+		return int(i), err == nil
+	default:
+		return 0, false
+	}
+}
@@ -3,6 +3,7 @@ package jsonutil_test
 import (
 	"encoding/json"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -715,3 +716,73 @@ func TestUnmarshalGraphQL_arrayInsideInlineFragment(t *testing.T) {
 		t.Error("not equal")
 	}
 }
+
+func TestDecodeGraphQL(t *testing.T) {
+	type query struct {
+		Me struct {
+			Name   string
+			Height float64
+		}
+	}
+
+	dec := json.NewDecoder(strings.NewReader(`{"data": {"me": {"name": "Luke Skywalker", "height": 1.72}}, "extensions": {"cost": 1}}`))
+	dec.UseNumber()
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		t.Fatalf("got token: %v, %v", tok, err)
+	}
+	if tok, err := dec.Token(); err != nil || tok != "data" {
+		t.Fatalf("got token: %v, %v", tok, err)
+	}
+
+	var got query
+	if err := jsonutil.DecodeGraphQL(dec, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	var want query
+	want.Me.Name = "Luke Skywalker"
+	want.Me.Height = 1.72
+	if !reflect.DeepEqual(got, want) {
+		t.Error("not equal")
+	}
+
+	// the rest of the stream can be decoded after the value.
+	if tok, err := dec.Token(); err != nil || tok != "extensions" {
+		t.Errorf("got token: %v, %v, want: extensions", tok, err)
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	type query struct {
+		Me struct {
+			Friends []struct {
+				Name string
+			} `graphql:"friends: people(first: 2)"`
+			Pet *struct {
+				Name string
+			}
+		}
+	}
+
+	var q query
+	if err := jsonutil.UnmarshalGraphQL([]byte(`{"me": {"friends": [{"name": "a"}, {"name": "b"}], "pet": null}}`), &q); err != nil {
+		t.Fatal(err)
+	}
+
+	values := jsonutil.ResolvePath(&q, []any{"me", "friends", float64(1), "name"})
+	if len(values) != 4 {
+		t.Fatalf("got %d values, want: 4", len(values))
+	}
+	if values[3].Addr().Interface() != &q.Me.Friends[1].Name {
+		t.Error("got the wrong field")
+	}
+
+	// the resolution stops at null values and indexes out of range.
+	if values := jsonutil.ResolvePath(&q, []any{"me", "pet", "name"}); len(values) != 2 {
+		t.Errorf("got %d values, want: 2", len(values))
+	}
+	if values := jsonutil.ResolvePath(&q, []any{"me", "friends", float64(2)}); len(values) != 2 {
+		t.Errorf("got %d values, want: 2", len(values))
+	}
+}
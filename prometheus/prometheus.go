@@ -0,0 +1,144 @@
+// Package prometheus implements the metrics recorder of the GraphQL client with Prometheus collectors.
+//
+//	recorder := prometheus.NewRecorder()
+//	prom.MustRegister(recorder)
+//
+//	client := graphql.NewClient("/graphql", http.DefaultClient, graphql.WithMetricsRecorder(recorder))
+package prometheus
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/hasura/go-graphql-client"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	labelOperationType = "operation_type"
+	labelOperationName = "operation_name"
+	labelStatusCode    = "status_code"
+	labelErrorCode     = "error_code"
+)
+
+// Option configures the recorder.
+type Option func(o *options)
+
+type options struct {
+	namespace   string
+	constLabels prom.Labels
+	buckets     []float64
+}
+
+// WithNamespace sets the namespace of metric names. Defaults to graphql_client.
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithConstLabels sets constant labels of all metrics.
+func WithConstLabels(labels prom.Labels) Option {
+	return func(o *options) {
+		o.constLabels = labels
+	}
+}
+
+// WithBuckets sets buckets of the latency histogram in seconds. Defaults to prometheus.DefBuckets.
+func WithBuckets(buckets []float64) Option {
+	return func(o *options) {
+		o.buckets = buckets
+	}
+}
+
+// Recorder records metrics of GraphQL operations to Prometheus collectors.
+// It implements both graphql.MetricsRecorder and prometheus.Collector.
+type Recorder struct {
+	requests *prom.CounterVec
+	duration *prom.HistogramVec
+	retries  *prom.CounterVec
+	errors   *prom.CounterVec
+}
+
+var (
+	_ graphql.MetricsRecorder = (*Recorder)(nil)
+	_ prom.Collector          = (*Recorder)(nil)
+)
+
+// NewRecorder creates a recorder. The recorder must be registered to a Prometheus registry to export metrics.
+func NewRecorder(opts ...Option) *Recorder {
+	o := options{
+		namespace: "graphql_client",
+		buckets:   prom.DefBuckets,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	operationLabels := []string{labelOperationType, labelOperationName}
+
+	return &Recorder{
+		requests: prom.NewCounterVec(prom.CounterOpts{
+			Namespace:   o.namespace,
+			Name:        "requests_total",
+			Help:        "Total number of GraphQL operations by the HTTP status of the last attempt.",
+			ConstLabels: o.constLabels,
+		}, append(operationLabels, labelStatusCode)),
+		duration: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace:   o.namespace,
+			Name:        "request_duration_seconds",
+			Help:        "Latency of GraphQL operations, including retries.",
+			ConstLabels: o.constLabels,
+			Buckets:     o.buckets,
+		}, operationLabels),
+		retries: prom.NewCounterVec(prom.CounterOpts{
+			Namespace:   o.namespace,
+			Name:        "retries_total",
+			Help:        "Total number of retried HTTP requests of GraphQL operations.",
+			ConstLabels: o.constLabels,
+		}, operationLabels),
+		errors: prom.NewCounterVec(prom.CounterOpts{
+			Namespace:   o.namespace,
+			Name:        "errors_total",
+			Help:        "Total number of GraphQL errors by the extensions.code value.",
+			ConstLabels: o.constLabels,
+		}, append(operationLabels, labelErrorCode)),
+	}
+}
+
+// RecordOperation implements the graphql.MetricsRecorder interface.
+func (r *Recorder) RecordOperation(_ context.Context, metrics graphql.OperationMetrics) {
+	operationType := string(metrics.OperationType)
+
+	r.requests.WithLabelValues(operationType, metrics.OperationName, strconv.Itoa(metrics.StatusCode)).Inc()
+	r.duration.WithLabelValues(operationType, metrics.OperationName).Observe(metrics.Duration.Seconds())
+
+	if metrics.Attempts > 1 {
+		r.retries.WithLabelValues(operationType, metrics.OperationName).Add(float64(metrics.Attempts - 1))
+	}
+
+	for _, code := range metrics.ErrorCodes {
+		if code == "" {
+			code = "unknown"
+		}
+
+		r.errors.WithLabelValues(operationType, metrics.OperationName, code).Inc()
+	}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (r *Recorder) Describe(ch chan<- *prom.Desc) {
+	r.requests.Describe(ch)
+	r.duration.Describe(ch)
+	r.retries.Describe(ch)
+	r.errors.Describe(ch)
+}
+
+// Collect implements the prometheus.Collector interface.
+func (r *Recorder) Collect(ch chan<- prom.Metric) {
+	r.requests.Collect(ch)
+	r.duration.Collect(ch)
+	r.retries.Collect(ch)
+	r.errors.Collect(ch)
+}
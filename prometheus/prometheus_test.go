@@ -0,0 +1,62 @@
+package prometheus_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+	"github.com/hasura/go-graphql-client/prometheus"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRecorder(t *testing.T) {
+	recorder := prometheus.NewRecorder(prometheus.WithNamespace("test"))
+	registry := prom.NewRegistry()
+	registry.MustRegister(recorder)
+
+	recorder.RecordOperation(context.Background(), graphql.OperationMetrics{
+		OperationType: graphql.OperationTypeQuery,
+		OperationName: "GetUser",
+		Duration:      100 * time.Millisecond,
+		Attempts:      3,
+		StatusCode:    200,
+		ErrorCodes:    []string{"FORBIDDEN", ""},
+	})
+	recorder.RecordOperation(context.Background(), graphql.OperationMetrics{
+		OperationType: graphql.OperationTypeQuery,
+		OperationName: "GetUser",
+		Duration:      50 * time.Millisecond,
+		Attempts:      1,
+		StatusCode:    200,
+	})
+
+	expected := `
+# HELP test_errors_total Total number of GraphQL errors by the extensions.code value.
+# TYPE test_errors_total counter
+test_errors_total{error_code="FORBIDDEN",operation_name="GetUser",operation_type="query"} 1
+test_errors_total{error_code="unknown",operation_name="GetUser",operation_type="query"} 1
+# HELP test_requests_total Total number of GraphQL operations by the HTTP status of the last attempt.
+# TYPE test_requests_total counter
+test_requests_total{operation_name="GetUser",operation_type="query",status_code="200"} 2
+# HELP test_retries_total Total number of retried HTTP requests of GraphQL operations.
+# TYPE test_retries_total counter
+test_retries_total{operation_name="GetUser",operation_type="query"} 2
+`
+	err := testutil.GatherAndCompare(
+		registry,
+		strings.NewReader(expected),
+		"test_errors_total",
+		"test_requests_total",
+		"test_retries_total",
+	)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if got := testutil.CollectAndCount(recorder, "test_request_duration_seconds"); got != 1 {
+		t.Errorf("got %d duration series, want: 1", got)
+	}
+}
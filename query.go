@@ -10,6 +10,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hasura/go-graphql-client/ident"
 )
@@ -19,6 +20,11 @@ type constructOptionsOutput struct {
 	operationDirectives []string
 	extensions          any
 	headers             *http.Header
+	cacheTTL            *time.Duration
+	errorPolicy         ErrorPolicy
+	errors              *Errors
+	// the header of the request, set by operations which need extra headers, e.g. incremental delivery
+	requestHeader http.Header
 }
 
 func (coo constructOptionsOutput) OperationDirectivesString() string {
@@ -41,6 +47,13 @@ func constructOptions(options []Option) (*constructOptionsOutput, error) {
 			output.extensions = opt.value
 		case bindResponseHeadersOption:
 			output.headers = opt.value
+		case cacheTTLOption:
+			ttl := opt.ttl
+			output.cacheTTL = &ttl
+		case ErrorPolicy:
+			output.errorPolicy = opt
+		case bindErrorsOption:
+			output.errors = opt.value
 		default:
 			if opt.Type() != OptionTypeOperationDirective {
 				return nil, fmt.Errorf("invalid query option type: %s", option.Type())
@@ -197,6 +210,95 @@ func ConstructSubscription(
 	), optionsOutput.operationName, nil
 }
 
+// parseOperationType detects the type of the first operation in a pre-built GraphQL document.
+// Fragment definitions are skipped. Operations written in the shorthand form are queries.
+func parseOperationType(query string) operationType {
+	depth := 0
+	inFragment := false
+
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+
+		switch {
+		case ch == '#':
+			// skip comments until the end of line
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case ch == '"':
+			i = skipStringValue(query, i)
+		case ch == '{':
+			if depth == 0 && !inFragment {
+				return queryOperation
+			}
+
+			depth++
+		case ch == '}':
+			depth--
+			if depth == 0 {
+				inFragment = false
+			}
+		case depth == 0 && !inFragment && isNameStart(ch):
+			start := i
+			for i+1 < len(query) && isNameContinue(query[i+1]) {
+				i++
+			}
+
+			switch query[start : i+1] {
+			case "query":
+				return queryOperation
+			case "mutation":
+				return mutationOperation
+			case "subscription":
+				return subscriptionOperation
+			case "fragment":
+				inFragment = true
+			}
+		}
+	}
+
+	return queryOperation
+}
+
+// skipStringValue returns the index of the closing quote of the string value which starts at index start.
+// Both regular and block strings are supported.
+func skipStringValue(query string, start int) int {
+	if strings.HasPrefix(query[start:], `"""`) {
+		for i := start + 3; i < len(query); i++ {
+			if query[i] == '\\' && strings.HasPrefix(query[i+1:], `"""`) {
+				i += 3
+
+				continue
+			}
+
+			if strings.HasPrefix(query[i:], `"""`) {
+				return i + 2
+			}
+		}
+
+		return len(query)
+	}
+
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+
+	return len(query)
+}
+
+func isNameStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isNameContinue(ch byte) bool {
+	return isNameStart(ch) || (ch >= '0' && ch <= '9')
+}
+
 // queryArguments constructs a minified arguments string for variables.
 //
 // E.g., map[string]interface{}{"a": int(123), "b": true} -> "$a:Int!$b:Boolean!".
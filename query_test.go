@@ -651,6 +651,31 @@ func TestConstructSubscription(t *testing.T) {
 	}
 }
 
+func TestParseOperationType(t *testing.T) {
+	tests := []struct {
+		in   string
+		want operationType
+	}{
+		{in: `{viewer{login}}`, want: queryOperation},
+		{in: `query GetUser($id: ID!) {user(id: $id){name}}`, want: queryOperation},
+		{in: `  # mutation in comment
+		query { a }`, want: queryOperation},
+		{in: `mutation{addReaction{clientMutationId}}`, want: mutationOperation},
+		{in: `subscription OnReview {review{stars}}`, want: subscriptionOperation},
+		{
+			in:   `fragment UserFields on User @include(if: true) { query } mutation UpdateUser { updateUser { ...UserFields } }`,
+			want: mutationOperation,
+		},
+		{in: `query ($s: String = "mutation {") { echo(s: $s) }`, want: queryOperation},
+		{in: `mutation @label(text: """{ block "string" \""" }""") { a }`, want: mutationOperation},
+	}
+	for _, tc := range tests {
+		if got := parseOperationType(tc.in); got != tc.want {
+			t.Errorf("parseOperationType(%q): got %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
 func TestQueryArguments(t *testing.T) {
 	iVal := int(123)
 	i8Val := int8(12)
@@ -797,6 +822,14 @@ func TestQueryArguments(t *testing.T) {
 			},
 			want: `$id:uuid!$id_optional:uuid$ids:[uuid!]!$ids_optional:[uuid]!$my_uuid:my_uuid!$review:user_review!$review_input:user_review_input!`,
 		},
+		{
+			in: map[string]interface{}{
+				"file":          Upload{},
+				"file_optional": &Upload{},
+				"files":         []Upload{},
+			},
+			want: `$file:Upload!$file_optional:Upload$files:[Upload!]!`,
+		},
 	}
 	for i, tc := range tests {
 		got := queryArguments(tc.in)
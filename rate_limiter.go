@@ -0,0 +1,156 @@
+package graphql
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket limiter of request attempts, which is shared by all operations of the client.
+// It adapts from the Retry-After and X-RateLimit-Remaining/X-RateLimit-Reset response headers,
+// so all in-flight and future requests back off together until the rate limit is reset.
+//
+// The limiter is shared by all copies of the client, and can be shared between clients of the same server.
+type RateLimiter struct {
+	mu sync.Mutex
+	// tokens per second, unlimited if zero
+	rate  float64
+	burst float64
+	// available tokens at the last update
+	tokens float64
+	last   time.Time
+	// requests are blocked until the time, learned from response headers
+	blockedUntil time.Time
+}
+
+// NewRateLimiter creates a token bucket rate limiter which allows rate requests per second with bursts of up to burst requests.
+// If the rate is zero, requests are only limited by the rate limit headers of responses.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// WithRateLimiter creates an option to limit request attempts, including retries, with the rate limiter.
+func WithRateLimiter(limiter *RateLimiter) ClientOption {
+	return func(c *Client) {
+		c.rateLimiter = limiter
+	}
+}
+
+// BlockedUntil returns the time until which requests are blocked by the rate limit headers of responses,
+// zero if requests aren't blocked.
+func (rl *RateLimiter) BlockedUntil() time.Time {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if time.Now().Before(rl.blockedUntil) {
+		return rl.blockedUntil
+	}
+
+	return time.Time{}
+}
+
+// wait blocks until a request can be sent, returns early with the error if the context is done.
+func (rl *RateLimiter) wait(ctx context.Context) error {
+	for {
+		delay := rl.reserve()
+		if delay <= 0 {
+			return nil
+		}
+
+		if err := sleepWithContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve takes a token if it's available, or returns the delay until the next token.
+func (rl *RateLimiter) reserve() time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(rl.blockedUntil) {
+		return rl.blockedUntil.Sub(now)
+	}
+
+	if rl.rate <= 0 {
+		return 0
+	}
+
+	rl.tokens = math.Min(rl.burst, rl.tokens+now.Sub(rl.last).Seconds()*rl.rate)
+	rl.last = now
+
+	if rl.tokens >= 1 {
+		rl.tokens--
+
+		return 0
+	}
+
+	return time.Duration((1 - rl.tokens) / rl.rate * float64(time.Second))
+}
+
+// update blocks requests if the response says the rate limit is exceeded.
+func (rl *RateLimiter) update(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+
+	until, ok := rateLimitedUntil(resp.Header)
+	if !ok {
+		return
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if until.After(rl.blockedUntil) {
+		rl.blockedUntil = until
+	}
+}
+
+// rateLimitedUntil finds the time until which the rate limit is exceeded from response headers.
+// The Retry-After header is prioritized. Otherwise, the limit is exceeded if X-RateLimit-Remaining is 0.
+// X-RateLimit-Reset can be either the unix time in seconds, e.g. GitHub, or the number of seconds until the reset.
+func rateLimitedUntil(header http.Header) (time.Time, bool) {
+	if retryAfter, ok := parseRetryAfter(header); ok {
+		return time.Now().Add(retryAfter), true
+	}
+
+	remaining, err := strconv.ParseFloat(header.Get("X-RateLimit-Remaining"), 64)
+	if err != nil || remaining > 0 {
+		return time.Time{}, false
+	}
+
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil || reset <= 0 {
+		return time.Time{}, false
+	}
+
+	// values after 2001-09-09 are unix times, smaller values are delta seconds.
+	if reset >= 1e9 {
+		return time.Unix(reset, 0), true
+	}
+
+	return time.Now().Add(time.Duration(reset) * time.Second), true
+}
+
+// waitRateLimit waits for the rate limiter of the client before the request attempt.
+func (c *Client) waitRateLimit(ctx context.Context) error {
+	if c.rateLimiter == nil {
+		return nil
+	}
+
+	return c.rateLimiter.wait(ctx)
+}
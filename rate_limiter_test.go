@@ -0,0 +1,136 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+func TestClientOption_WithRateLimiter(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRateLimiter(graphql.NewRateLimiter(20, 1)),
+	)
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := client.Query(context.Background(), &q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the first request uses the burst token, the next ones wait for 50ms each.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("got elapsed: %s, want at least 100ms", elapsed)
+	}
+}
+
+func TestClientOption_WithRateLimiter_headers(t *testing.T) {
+	testCases := []struct {
+		name    string
+		header  http.Header
+		blocked time.Duration
+	}{
+		{
+			name:    "retry after",
+			header:  http.Header{"Retry-After": []string{"30"}},
+			blocked: 30 * time.Second,
+		},
+		{
+			name: "reset seconds",
+			header: http.Header{
+				"X-Ratelimit-Remaining": []string{"0"},
+				"X-Ratelimit-Reset":     []string{"60"},
+			},
+			blocked: time.Minute,
+		},
+		{
+			name: "reset unix time",
+			header: http.Header{
+				"X-Ratelimit-Remaining": []string{"0"},
+				"X-Ratelimit-Reset":     []string{strconv.FormatInt(time.Now().Add(2*time.Minute).Unix(), 10)},
+			},
+			blocked: 2 * time.Minute,
+		},
+		{
+			name: "remaining",
+			header: http.Header{
+				"X-Ratelimit-Remaining": []string{"10"},
+				"X-Ratelimit-Reset":     []string{"60"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests int32
+			mux := http.NewServeMux()
+			mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				for key, values := range tc.header {
+					w.Header()[key] = values
+				}
+				w.Header().Set("Content-Type", "application/json")
+				mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+			})
+
+			limiter := graphql.NewRateLimiter(0, 1)
+			client := graphql.NewClient(
+				"/graphql",
+				&http.Client{Transport: localRoundTripper{handler: mux}},
+				graphql.WithRateLimiter(limiter),
+			)
+
+			var q struct {
+				User struct {
+					Name string
+				}
+			}
+			if err := client.Query(context.Background(), &q, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			blockedUntil := limiter.BlockedUntil()
+			if tc.blocked == 0 {
+				if !blockedUntil.IsZero() {
+					t.Errorf("got blocked until: %s, want: zero", blockedUntil)
+				}
+
+				return
+			}
+
+			if delay := time.Until(blockedUntil); delay < tc.blocked-2*time.Second || delay > tc.blocked {
+				t.Errorf("got blocked for: %s, want: %s", delay, tc.blocked)
+			}
+
+			// other calls back off until the limit is reset.
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if err := client.Query(ctx, &q, nil); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("got error: %v, want: context deadline exceeded", err)
+			}
+			if got := atomic.LoadInt32(&requests); got != 1 {
+				t.Errorf("got %d requests, want: 1", got)
+			}
+		})
+	}
+}
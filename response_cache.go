@@ -0,0 +1,327 @@
+package graphql
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This file implements the response cache of query operations.
+// Unlike the normalized cache, results are stored as-is, keyed by the query, variables and operation name.
+
+const defaultMemoryCacheCapacity = 1000
+
+// CacheStore is the storage of the response cache.
+// Stores must be safe for concurrent use. Failures of the storage are treated as cache misses.
+type CacheStore interface {
+	// Get returns the value of the key if exists and isn't expired.
+	Get(key string) ([]byte, bool)
+	// Set stores the value of the key with the time to live.
+	Set(key string, value []byte, ttl time.Duration)
+	// Delete removes the key.
+	Delete(key string)
+}
+
+// WithResponseCache creates an option to cache results of query operations in the store.
+// If the store is nil, an in-memory LRU store of 1000 entries is used.
+//
+// The TTL of the result is decided in order: the CacheTTL option of the operation,
+// the max-age directive of the Cache-Control response header, then the default ttl.
+// Results with errors and responses with the no-store or no-cache directive aren't cached.
+//
+// The cache is a middleware which is appended to the middleware chain.
+// Request headers aren't a part of the cache key, don't share the store between users of private data.
+func WithResponseCache(store CacheStore, ttl time.Duration) ClientOption {
+	if store == nil {
+		store = NewMemoryCacheStore(defaultMemoryCacheCapacity)
+	}
+
+	return WithMiddleware(newResponseCacheMiddleware(store, ttl))
+}
+
+// cachedResponse is the stored value of the response cache.
+type cachedResponse struct {
+	Data       json.RawMessage `json:"data"`
+	Extensions json.RawMessage `json:"extensions,omitempty"`
+}
+
+func newResponseCacheMiddleware(store CacheStore, defaultTTL time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) *Response {
+			if req.OperationType != OperationTypeQuery || (req.cacheTTL != nil && *req.cacheTTL <= 0) {
+				return next(ctx, req)
+			}
+
+			key, err := responseCacheKey(req)
+			if err != nil {
+				return next(ctx, req)
+			}
+
+			if value, ok := store.Get(key); ok {
+				var cached cachedResponse
+				if err := json.Unmarshal(value, &cached); err == nil {
+					return &Response{
+						Data:       cached.Data,
+						Extensions: cached.Extensions,
+					}
+				}
+			}
+
+			resp := next(ctx, req)
+			if resp == nil || len(resp.Errors) > 0 || len(resp.Data) == 0 {
+				return resp
+			}
+
+			ttl := defaultTTL
+
+			if resp.HTTPResponse != nil {
+				if maxAge, ok := cacheControlMaxAge(resp.HTTPResponse.Header); ok {
+					ttl = maxAge
+				}
+			}
+
+			if req.cacheTTL != nil {
+				ttl = *req.cacheTTL
+			}
+
+			if ttl <= 0 {
+				return resp
+			}
+
+			value, err := json.Marshal(cachedResponse{
+				Data:       resp.Data,
+				Extensions: resp.Extensions,
+			})
+			if err == nil {
+				store.Set(key, value, ttl)
+			}
+
+			return resp
+		}
+	}
+}
+
+// responseCacheKey returns the SHA-256 hash of the query, variables and operation name.
+func responseCacheKey(req *Request) (string, error) {
+	payload, err := json.Marshal(GraphQLRequestPayload{
+		Query:         req.Query,
+		Variables:     req.Variables,
+		OperationName: req.OperationName,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(payload)
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// cacheControlMaxAge returns the max-age directive of the Cache-Control header.
+// The no-store and no-cache directives are treated as zero max age.
+func cacheControlMaxAge(header http.Header) (time.Duration, bool) {
+	value := header.Get("Cache-Control")
+	if value == "" {
+		return 0, false
+	}
+
+	var maxAge time.Duration
+
+	found := false
+
+	for _, directive := range strings.Split(value, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache":
+			return 0, true
+		case "max-age":
+			seconds, err := strconv.ParseInt(strings.Trim(arg, `"`), 10, 64)
+			if err != nil {
+				continue
+			}
+
+			maxAge = time.Duration(seconds) * time.Second
+			found = true
+		}
+	}
+
+	return maxAge, found
+}
+
+// MemoryCacheStore is an in-memory cache store which evicts the least recently used entry
+// when the capacity is reached.
+type MemoryCacheStore struct {
+	capacity int
+	mu       sync.Mutex
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+type memoryCacheItem struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+var _ CacheStore = (*MemoryCacheStore)(nil)
+
+// NewMemoryCacheStore creates an in-memory LRU cache store.
+// The capacity is unlimited if the value is zero or negative.
+func NewMemoryCacheStore(capacity int) *MemoryCacheStore {
+	return &MemoryCacheStore{
+		capacity: capacity,
+		items:    map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+// Get implements the CacheStore interface.
+func (s *MemoryCacheStore) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	item := elem.Value.(*memoryCacheItem)
+	if time.Now().After(item.expiresAt) {
+		s.remove(elem)
+
+		return nil, false
+	}
+
+	s.order.MoveToFront(elem)
+
+	return item.value, true
+}
+
+// Set implements the CacheStore interface.
+func (s *MemoryCacheStore) Set(key string, value []byte, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item := &memoryCacheItem{
+		key:       key,
+		value:     value,
+		expiresAt: time.Now().Add(ttl),
+	}
+
+	if elem, ok := s.items[key]; ok {
+		elem.Value = item
+		s.order.MoveToFront(elem)
+
+		return
+	}
+
+	s.items[key] = s.order.PushFront(item)
+
+	if s.capacity > 0 && s.order.Len() > s.capacity {
+		s.remove(s.order.Back())
+	}
+}
+
+// Delete implements the CacheStore interface.
+func (s *MemoryCacheStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.items[key]; ok {
+		s.remove(elem)
+	}
+}
+
+// Len returns the number of stored entries, including expired entries which aren't evicted yet.
+func (s *MemoryCacheStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.order.Len()
+}
+
+func (s *MemoryCacheStore) remove(elem *list.Element) {
+	s.order.Remove(elem)
+	delete(s.items, elem.Value.(*memoryCacheItem).key)
+}
+
+// DiskCacheStore is a cache store which persists entries as files in the directory,
+// so results are reused between runs of CLI applications.
+// Every entry is a file named by the hash of the key, with the expiry time before the value.
+type DiskCacheStore struct {
+	dir string
+}
+
+var _ CacheStore = (*DiskCacheStore)(nil)
+
+// NewDiskCacheStore creates a cache store in the directory. The directory is created if not exists.
+func NewDiskCacheStore(dir string) (*DiskCacheStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	return &DiskCacheStore{dir: dir}, nil
+}
+
+// Get implements the CacheStore interface.
+func (s *DiskCacheStore) Get(key string) ([]byte, bool) {
+	content, err := os.ReadFile(s.path(key))
+	if err != nil || len(content) < 8 {
+		return nil, false
+	}
+
+	expiresAt := time.Unix(0, int64(binary.BigEndian.Uint64(content[:8])))
+	if time.Now().After(expiresAt) {
+		s.Delete(key)
+
+		return nil, false
+	}
+
+	return content[8:], true
+}
+
+// Set implements the CacheStore interface.
+// The file is written to a temporary file and renamed, so readers never see partial entries.
+func (s *DiskCacheStore) Set(key string, value []byte, ttl time.Duration) {
+	file, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		_ = os.Remove(file.Name())
+	}()
+
+	var expiresAt [8]byte
+
+	binary.BigEndian.PutUint64(expiresAt[:], uint64(time.Now().Add(ttl).UnixNano()))
+
+	_, err = file.Write(append(expiresAt[:], value...))
+	if closeErr := file.Close(); err != nil || closeErr != nil {
+		return
+	}
+
+	_ = os.Rename(file.Name(), s.path(key))
+}
+
+// Delete implements the CacheStore interface.
+func (s *DiskCacheStore) Delete(key string) {
+	_ = os.Remove(s.path(key))
+}
+
+func (s *DiskCacheStore) path(key string) string {
+	hash := sha256.Sum256([]byte(key))
+
+	return filepath.Join(s.dir, hex.EncodeToString(hash[:]))
+}
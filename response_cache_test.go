@@ -0,0 +1,178 @@
+package graphql_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+func newResponseCacheTestClient(
+	t *testing.T,
+	cacheControl string,
+	requests *int,
+	options ...graphql.ClientOption,
+) *graphql.Client {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		_, _ = io.Copy(io.Discard, req.Body)
+		*requests++
+		w.Header().Set("Content-Type", "application/json")
+		if cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		mustWrite(w, `{"data": {"flag": {"enabled": true}}, "extensions": {"version": 1}}`)
+	})
+
+	return graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		options...,
+	)
+}
+
+type featureFlagQuery struct {
+	Flag struct {
+		Enabled bool
+	} `graphql:"flag(name: $name)"`
+}
+
+func TestClientOption_WithResponseCache(t *testing.T) {
+	requests := 0
+	client := newResponseCacheTestClient(t, "", &requests, graphql.WithResponseCache(nil, time.Minute))
+
+	for i := 0; i < 3; i++ {
+		var q featureFlagQuery
+		var ext struct {
+			Version int `json:"version"`
+		}
+		err := client.Query(context.Background(), &q, map[string]any{"name": "beta"}, graphql.BindExtensions(&ext))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !q.Flag.Enabled || ext.Version != 1 {
+			t.Errorf("got enabled: %t, version: %d, want: true, 1", q.Flag.Enabled, ext.Version)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want: 1", requests)
+	}
+
+	// other variables are cached separately.
+	var q featureFlagQuery
+	if err := client.Query(context.Background(), &q, map[string]any{"name": "alpha"}); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Errorf("got %d requests, want: 2", requests)
+	}
+
+	// mutations are never cached.
+	var m struct {
+		ToggleFlag bool
+	}
+	for i := 0; i < 2; i++ {
+		_ = client.Mutate(context.Background(), &m, nil)
+	}
+	if requests != 4 {
+		t.Errorf("got %d requests, want: 4", requests)
+	}
+}
+
+func TestClientOption_WithResponseCache_cacheControl(t *testing.T) {
+	testCases := []struct {
+		name         string
+		cacheControl string
+		options      []graphql.Option
+		wantRequests int
+	}{
+		{"max-age overrides the default ttl", "public, max-age=60", nil, 1},
+		{"max-age=0", "max-age=0", nil, 2},
+		{"no-store", "no-store", nil, 2},
+		{"the operation ttl overrides no-store", "no-store", []graphql.Option{graphql.CacheTTL(time.Minute)}, 1},
+		{"zero operation ttl disables the cache", "max-age=60", []graphql.Option{graphql.CacheTTL(0)}, 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := 0
+			// the default ttl is zero, so results are cached only by the header or option.
+			client := newResponseCacheTestClient(t, tc.cacheControl, &requests, graphql.WithResponseCache(nil, 0))
+
+			for i := 0; i < 2; i++ {
+				var q featureFlagQuery
+				if err := client.Query(context.Background(), &q, map[string]any{"name": "beta"}, tc.options...); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if requests != tc.wantRequests {
+				t.Errorf("got %d requests, want: %d", requests, tc.wantRequests)
+			}
+		})
+	}
+}
+
+func TestMemoryCacheStore(t *testing.T) {
+	store := graphql.NewMemoryCacheStore(2)
+	store.Set("a", []byte("1"), time.Minute)
+	store.Set("b", []byte("2"), time.Minute)
+
+	// a is the most recently used entry, so b is evicted.
+	if _, ok := store.Get("a"); !ok {
+		t.Fatal("got a: not found")
+	}
+	store.Set("c", []byte("3"), time.Minute)
+
+	if _, ok := store.Get("b"); ok {
+		t.Error("got b, want evicted")
+	}
+	if value, ok := store.Get("c"); !ok || string(value) != "3" {
+		t.Errorf("got c: %q, want: 3", value)
+	}
+
+	store.Set("d", []byte("4"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := store.Get("d"); ok {
+		t.Error("got d, want expired")
+	}
+
+	if store.Len() != 1 {
+		t.Errorf("got %d entries, want: 1", store.Len())
+	}
+
+	store.Delete("c")
+	if store.Len() != 0 {
+		t.Errorf("got %d entries, want: 0", store.Len())
+	}
+}
+
+func TestDiskCacheStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := graphql.NewDiskCacheStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store.Set("query", []byte(`{"data": {}}`), time.Minute)
+	store.Set("expired", []byte(`{"data": {}}`), -time.Second)
+
+	// entries are persisted between stores of the same directory.
+	store2, err := graphql.NewDiskCacheStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := store2.Get("query"); !ok || string(value) != `{"data": {}}` {
+		t.Errorf("got value: %q, want: {\"data\": {}}", value)
+	}
+	if _, ok := store2.Get("expired"); ok {
+		t.Error("got the expired entry")
+	}
+
+	store2.Delete("query")
+	if _, ok := store.Get("query"); ok {
+		t.Error("got the deleted entry")
+	}
+}
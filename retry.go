@@ -0,0 +1,220 @@
+package graphql
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const defaultIdempotencyKeyHeader = "Idempotency-Key"
+
+// RetryPolicy decides whether and when the failed request attempt is retried.
+// Mutations are retried only if the idempotency key header is attached, see WithIdempotencyKey.
+type RetryPolicy interface {
+	// ShouldRetry returns the delay before the next attempt, or false to stop retrying.
+	ShouldRetry(attempt RetryAttempt) (time.Duration, bool)
+}
+
+// RetryAttempt holds information of the failed request attempt.
+type RetryAttempt struct {
+	OperationType OperationType
+	// Attempt is the number of the failed attempt, starting from 1.
+	Attempt int
+	// Elapsed is the duration since the first attempt started.
+	Elapsed time.Duration
+	// Response is the HTTP response of the attempt, nil if the request failed without a response.
+	// The body is already read and closed.
+	Response *http.Response
+	// Errors of the attempt. If the HTTP status is successful, they are GraphQL errors of the response.
+	Errors Errors
+}
+
+// BackoffRetryPolicy retries failed attempts with exponential backoff delays.
+// The Retry-After response header is prioritized over the backoff delay if it exists.
+type BackoffRetryPolicy struct {
+	// MaxRetries is the max number of retries.
+	MaxRetries int
+	// BaseDelay is the delay of the first retry. Default 1 second.
+	BaseDelay time.Duration
+	// ExponentialRate is the multiplier of the delay for every retry. Default 2.0.
+	ExponentialRate float64
+	// MaxDelay caps the backoff delay. The operation isn't retried if the Retry-After header requires a longer delay.
+	// The delay is unlimited if the value is zero.
+	MaxDelay time.Duration
+	// Jitter randomly reduces the backoff delay by up to the fraction, from 0 to 1,
+	// so retries of many clients don't hit the server at the same time.
+	Jitter float64
+	// Budget is the overall time of retries of an operation.
+	// The operation isn't retried if the next attempt would start after the budget since the first attempt.
+	// The budget is unlimited if the value is zero.
+	Budget time.Duration
+	// HTTPStatus is the list of retryable HTTP statuses. Default: 429, 502, 503, 504.
+	HTTPStatus []int
+	// RetryOnGraphQLError checks if the GraphQL errors of a successful HTTP response are retryable.
+	RetryOnGraphQLError func(errs Errors) bool
+	// RetryOnNetworkError retries if the request fails without a response, e.g. the connection is refused.
+	RetryOnNetworkError bool
+}
+
+var _ RetryPolicy = (*BackoffRetryPolicy)(nil)
+
+// NewBackoffRetryPolicy creates a backoff retry policy with default settings.
+func NewBackoffRetryPolicy(maxRetries int) *BackoffRetryPolicy {
+	return &BackoffRetryPolicy{
+		MaxRetries:      maxRetries,
+		BaseDelay:       time.Second,
+		ExponentialRate: 2,
+		HTTPStatus: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// ShouldRetry implements the RetryPolicy interface.
+func (p *BackoffRetryPolicy) ShouldRetry(attempt RetryAttempt) (time.Duration, bool) {
+	if attempt.Attempt > p.MaxRetries || !p.isRetryable(attempt) {
+		return 0, false
+	}
+
+	delay, ok := p.delay(attempt)
+	if !ok || (p.Budget > 0 && attempt.Elapsed+delay > p.Budget) {
+		return 0, false
+	}
+
+	return delay, true
+}
+
+func (p *BackoffRetryPolicy) isRetryable(attempt RetryAttempt) bool {
+	switch {
+	case attempt.Response == nil:
+		return p.RetryOnNetworkError
+	case attempt.Response.StatusCode >= 400:
+		for _, status := range p.HTTPStatus {
+			if status == attempt.Response.StatusCode {
+				return true
+			}
+		}
+
+		return false
+	default:
+		return p.RetryOnGraphQLError != nil && p.RetryOnGraphQLError(attempt.Errors)
+	}
+}
+
+// delay returns the delay before the next attempt.
+// The delay of the Retry-After header is at least the base delay.
+func (p *BackoffRetryPolicy) delay(attempt RetryAttempt) (time.Duration, bool) {
+	if attempt.Response != nil {
+		if retryAfter, ok := parseRetryAfter(attempt.Response.Header); ok {
+			delay := time.Duration(math.Max(float64(retryAfter), float64(p.BaseDelay)))
+
+			return delay, p.MaxDelay <= 0 || delay <= p.MaxDelay
+		}
+	}
+
+	delay := float64(p.BaseDelay) * math.Pow(p.ExponentialRate, float64(attempt.Attempt-1))
+	if p.MaxDelay > 0 {
+		delay = math.Min(delay, float64(p.MaxDelay))
+	}
+
+	if p.Jitter > 0 {
+		delay -= delay * math.Min(p.Jitter, 1) * rand.Float64() //nolint:gosec
+	}
+
+	return time.Duration(delay), true
+}
+
+// The HTTP [Retry-After] response header indicates how long the user agent should wait before making a follow-up request.
+// The client finds this header if exist and decodes to duration.
+//
+// [Retry-After]: https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Retry-After
+func parseRetryAfter(header http.Header) (time.Duration, bool) {
+	rawRetryAfter := header.Get("Retry-After")
+	if rawRetryAfter == "" {
+		return 0, false
+	}
+
+	// A non-negative decimal integer indicating the seconds to delay after the response is received.
+	retryAfterSecs, err := strconv.ParseInt(rawRetryAfter, 10, 32)
+	if err == nil && retryAfterSecs > 0 {
+		return time.Duration(retryAfterSecs) * time.Second, true
+	}
+
+	// A date after which to retry, e.g. Tue, 29 Oct 2024 16:56:32 GMT
+	retryTime, err := time.Parse(time.RFC1123, rawRetryAfter)
+	if err == nil && retryTime.After(time.Now()) {
+		return time.Until(retryTime), true
+	}
+
+	return 0, false
+}
+
+// WithRetryPolicy creates an option to retry failed requests with the policy.
+// The deprecated WithRetry options only change a BackoffRetryPolicy, and don't modify the policy which is passed here.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// WithIdempotencyKey creates an option to attach a unique key header to every mutation,
+// so the server can detect duplicated requests, and mutations can be retried by the retry policy.
+// The key is the same for all attempts of the mutation. The default header is Idempotency-Key if the name is empty.
+func WithIdempotencyKey(header string) ClientOption {
+	return func(c *Client) {
+		c.idempotencyKeyHeader = header
+
+		if header == "" {
+			c.idempotencyKeyHeader = defaultIdempotencyKeyHeader
+		}
+	}
+}
+
+// updateBackoffRetryPolicy applies the deprecated retry options to a copy of the backoff policy of the client,
+// so a policy which is shared with other clients isn't changed. The policy is created if the client doesn't have one.
+// A custom policy isn't changed.
+func (c *Client) updateBackoffRetryPolicy(update func(policy *BackoffRetryPolicy)) {
+	var policy BackoffRetryPolicy
+
+	switch current := c.retryPolicy.(type) {
+	case nil:
+		policy = *NewBackoffRetryPolicy(0)
+	case *BackoffRetryPolicy:
+		policy = *current
+	default:
+		return
+	}
+
+	update(&policy)
+	c.retryPolicy = &policy
+}
+
+// canRetry checks if the request can be retried.
+// Mutations are retried only if the idempotency key is attached.
+func (c *Client) canRetry(input graphqlHTTPRequest) bool {
+	if c.retryPolicy == nil {
+		return false
+	}
+
+	return input.op != mutationOperation ||
+		(c.idempotencyKeyHeader != "" && input.header.Get(c.idempotencyKeyHeader) != "")
+}
+
+// sleepWithContext waits for the duration, returns early with the error if the context is done.
+func sleepWithContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
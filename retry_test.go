@@ -0,0 +1,304 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+func TestBackoffRetryPolicy_ShouldRetry(t *testing.T) {
+	unavailable := &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}}
+	retryAfter := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"10"}}}
+
+	testCases := []struct {
+		name      string
+		policy    func(p *graphql.BackoffRetryPolicy)
+		attempt   graphql.RetryAttempt
+		wantDelay time.Duration
+		wantRetry bool
+	}{
+		{
+			name:      "exponential delay",
+			attempt:   graphql.RetryAttempt{Attempt: 3, Response: unavailable},
+			wantDelay: 4 * time.Second,
+			wantRetry: true,
+		},
+		{
+			name:    "max retries exceeded",
+			attempt: graphql.RetryAttempt{Attempt: 4, Response: unavailable},
+		},
+		{
+			name:      "max delay",
+			policy:    func(p *graphql.BackoffRetryPolicy) { p.MaxDelay = 3 * time.Second },
+			attempt:   graphql.RetryAttempt{Attempt: 3, Response: unavailable},
+			wantDelay: 3 * time.Second,
+			wantRetry: true,
+		},
+		{
+			name:      "retry after",
+			attempt:   graphql.RetryAttempt{Attempt: 1, Response: retryAfter},
+			wantDelay: 10 * time.Second,
+			wantRetry: true,
+		},
+		{
+			name:    "retry after exceeds the max delay",
+			policy:  func(p *graphql.BackoffRetryPolicy) { p.MaxDelay = 5 * time.Second },
+			attempt: graphql.RetryAttempt{Attempt: 1, Response: retryAfter},
+		},
+		{
+			name:    "budget exceeded",
+			policy:  func(p *graphql.BackoffRetryPolicy) { p.Budget = 5 * time.Second },
+			attempt: graphql.RetryAttempt{Attempt: 2, Elapsed: 4 * time.Second, Response: unavailable},
+		},
+		{
+			name:    "status isn't retryable",
+			attempt: graphql.RetryAttempt{Attempt: 1, Response: &http.Response{StatusCode: http.StatusBadRequest}},
+		},
+		{
+			name:    "network error",
+			attempt: graphql.RetryAttempt{Attempt: 1},
+		},
+		{
+			name:      "retry on network error",
+			policy:    func(p *graphql.BackoffRetryPolicy) { p.RetryOnNetworkError = true },
+			attempt:   graphql.RetryAttempt{Attempt: 1},
+			wantDelay: time.Second,
+			wantRetry: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := graphql.NewBackoffRetryPolicy(3)
+			if tc.policy != nil {
+				tc.policy(policy)
+			}
+
+			delay, ok := policy.ShouldRetry(tc.attempt)
+			if ok != tc.wantRetry || delay != tc.wantDelay {
+				t.Errorf("got: %s, %t, want: %s, %t", delay, ok, tc.wantDelay, tc.wantRetry)
+			}
+		})
+	}
+}
+
+func TestBackoffRetryPolicy_jitter(t *testing.T) {
+	policy := graphql.NewBackoffRetryPolicy(3)
+	policy.Jitter = 0.5
+
+	attempt := graphql.RetryAttempt{
+		Attempt:  2,
+		Response: &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}},
+	}
+	for i := 0; i < 100; i++ {
+		delay, ok := policy.ShouldRetry(attempt)
+		if !ok || delay < time.Second || delay > 2*time.Second {
+			t.Fatalf("got delay: %s, %t, want between 1s and 2s", delay, ok)
+		}
+	}
+}
+
+func TestClientOption_WithRetryPolicy_contextCanceled(t *testing.T) {
+	var attempts int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		http.Error(w, "temporary error", http.StatusServiceUnavailable)
+	})
+
+	policy := graphql.NewBackoffRetryPolicy(3)
+	policy.BaseDelay = time.Minute
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRetryPolicy(policy),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	start := time.Now()
+	err := client.Query(ctx, &q, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error: %v, want: context deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("got elapsed: %s, want the retry to stop when the context is done", elapsed)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Errorf("got %d attempts, want: 1", got)
+	}
+}
+
+type noRetryPolicy struct{}
+
+func (noRetryPolicy) ShouldRetry(attempt graphql.RetryAttempt) (time.Duration, bool) {
+	return 0, false
+}
+
+func TestClientOption_WithRetry_policy(t *testing.T) {
+	var attempts int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		http.Error(w, "temporary error", http.StatusServiceUnavailable)
+	})
+
+	query := func(options ...graphql.ClientOption) int32 {
+		atomic.StoreInt32(&attempts, 0)
+		client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, options...)
+
+		var q struct {
+			User struct {
+				Name string
+			}
+		}
+		if err := client.Query(context.Background(), &q, nil); err == nil {
+			t.Error("got error: nil, want: non-nil")
+		}
+
+		return atomic.LoadInt32(&attempts)
+	}
+
+	// the deprecated options don't modify the shared policy.
+	shared := graphql.NewBackoffRetryPolicy(3)
+	shared.BaseDelay = time.Millisecond
+	if got := query(graphql.WithRetryPolicy(shared), graphql.WithRetry(1)); got != 2 {
+		t.Errorf("got %d attempts, want: 2", got)
+	}
+	if shared.MaxRetries != 3 {
+		t.Errorf("got max retries of the shared policy: %d, want: 3", shared.MaxRetries)
+	}
+	if got := query(graphql.WithRetryPolicy(shared)); got != 4 {
+		t.Errorf("got %d attempts, want: 4", got)
+	}
+
+	// a custom policy isn't replaced.
+	if got := query(graphql.WithRetryPolicy(noRetryPolicy{}), graphql.WithRetry(3)); got != 1 {
+		t.Errorf("got %d attempts, want: 1", got)
+	}
+}
+
+func TestClientOption_WithIdempotencyKey(t *testing.T) {
+	var keys []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		keys = append(keys, req.Header.Get("Idempotency-Key"))
+		if len(keys)%2 == 1 {
+			http.Error(w, "temporary error", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"createUser": true}}`)
+	})
+
+	policy := graphql.NewBackoffRetryPolicy(1)
+	policy.BaseDelay = time.Millisecond
+
+	var m struct {
+		CreateUser bool
+	}
+
+	// mutations aren't retried without the idempotency key.
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRetryPolicy(policy),
+	)
+	if err := client.Mutate(context.Background(), &m, nil); err == nil {
+		t.Fatal("got error: nil, want: 503")
+	}
+	if len(keys) != 1 || keys[0] != "" {
+		t.Fatalf("got keys: %q, want one request without the key", keys)
+	}
+
+	keys = nil
+	client = graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRetryPolicy(policy),
+		graphql.WithIdempotencyKey(""),
+	)
+	if err := client.Mutate(context.Background(), &m, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Errorf("got keys: %q, want the same key for both attempts", keys)
+	}
+
+	// every mutation has a new key.
+	if err := client.Mutate(context.Background(), &m, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 4 || keys[2] == keys[0] {
+		t.Errorf("got keys: %q, want a new key", keys)
+	}
+}
+
+type closeTrackingBody struct {
+	io.Reader
+	closed *int32
+}
+
+func (b closeTrackingBody) Close() error {
+	atomic.AddInt32(b.closed, 1)
+
+	return nil
+}
+
+type closeTrackingRoundTripper struct {
+	attempts *int32
+	closed   *int32
+	t        *testing.T
+}
+
+func (rt closeTrackingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	attempt := atomic.AddInt32(rt.attempts, 1)
+	// the body of the previous attempt is closed before the next attempt.
+	if closed := atomic.LoadInt32(rt.closed); closed != attempt-1 {
+		rt.t.Errorf("attempt %d: got %d closed bodies, want: %d", attempt, closed, attempt-1)
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Header:     http.Header{},
+		Body:       closeTrackingBody{Reader: strings.NewReader("temporary error"), closed: rt.closed},
+		Request:    req,
+	}, nil
+}
+
+func TestClientOption_WithRetryPolicy_closeBody(t *testing.T) {
+	var attempts, closed int32
+
+	policy := graphql.NewBackoffRetryPolicy(2)
+	policy.BaseDelay = time.Millisecond
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: closeTrackingRoundTripper{attempts: &attempts, closed: &closed, t: t}},
+		graphql.WithRetryPolicy(policy),
+	)
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	if err := client.Query(context.Background(), &q, nil); err == nil {
+		t.Fatal("got error: nil, want: 503")
+	}
+	if attempts != 3 || closed != 3 {
+		t.Errorf("got %d attempts, %d closed bodies, want: 3, 3", attempts, closed)
+	}
+}
@@ -0,0 +1,191 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hasura/go-graphql-client/pkg/jsonutil"
+)
+
+// StreamQuery executes the query and calls fn for every element of the list at the path of the response data,
+// e.g. "orders.nodes", so huge lists are processed with constant memory.
+// The path is the list of response keys which are separated by dots, the alias is used if the field has one.
+//
+// The query is built from q as usual. Elements of the list are decoded one at a time into T,
+// with the same tag, fragment and union rules as the regular decoder, and the list field of q stays empty.
+// Other fields of the data, e.g. page info, are decoded into q.
+//
+// Streaming stops if fn returns an error, and the error is returned.
+// Elements which were delivered before an error of the response aren't rolled back,
+// they can be delivered again if the retry policy retries the operation.
+// The none error policy doesn't hold back elements either, because errors may follow the data in the response.
+func StreamQuery[T any](
+	ctx context.Context,
+	c *Client,
+	q any,
+	path string,
+	variables map[string]any,
+	fn func(item T) error,
+	options ...Option,
+) error {
+	keys := strings.Split(path, ".")
+	for _, key := range keys {
+		if key == "" {
+			return fmt.Errorf("invalid list path: %q", path)
+		}
+	}
+
+	query, optionsOutput, err := c.buildQueryAndOptions(queryOperation, q, variables, options...)
+	if err != nil {
+		return err
+	}
+
+	var callbackErr error
+
+	decodeItem := func(dec *json.Decoder) error {
+		var item T
+		if err := jsonutil.DecodeGraphQL(dec, &item); err != nil {
+			return err
+		}
+
+		if err := fn(item); err != nil {
+			callbackErr = err
+
+			return err
+		}
+
+		return nil
+	}
+
+	decodeAttempt := newAttemptTarget(q)
+	decode := func(dec *json.Decoder) error {
+		data, err := streamListValue(dec, keys, decodeItem)
+		if err != nil {
+			return err
+		}
+
+		return decodeAttempt(func(target any) error {
+			return jsonutil.UnmarshalGraphQL(data, target)
+		})
+	}
+
+	resp := c.doRequest(withStreamDecoder(ctx, decode), queryOperation, query, variables, optionsOutput)
+
+	// the data isn't streamed if a middleware responds without the server, e.g. the response cache.
+	if len(resp.Data) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(resp.Data))
+		dec.UseNumber()
+
+		if err := decode(dec); err != nil {
+			resp.streamErr = err
+		}
+
+		resp.streamedData = resp.hasData()
+		resp.Data = nil
+	}
+
+	err = c.processResponse(q, resp, optionsOutput)
+
+	if callbackErr != nil {
+		return callbackErr
+	}
+
+	return err
+}
+
+// streamListValue decodes the next JSON value and returns it without the list at the path.
+// Elements of the list are decoded by decodeItem.
+func streamListValue(
+	dec *json.Decoder,
+	path []string,
+	decodeItem func(dec *json.Decoder) error,
+) (json.RawMessage, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok == nil {
+		return json.RawMessage("null"), nil
+	}
+
+	if len(path) == 0 {
+		if tok != json.Delim('[') {
+			return nil, fmt.Errorf("the value at the list path isn't a list: %v", tok)
+		}
+
+		for dec.More() {
+			if err := decodeItem(dec); err != nil {
+				return nil, err
+			}
+		}
+
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+
+	if tok != json.Delim('{') {
+		return nil, fmt.Errorf("the value of %q isn't an object: %v", path[0], tok)
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		key, ok := tok.(string)
+		if !ok {
+			return nil, errors.New("unexpected non-key in JSON input")
+		}
+
+		var value json.RawMessage
+
+		if key == path[0] {
+			value, err = streamListValue(dec, path[1:], decodeItem)
+		} else {
+			err = dec.Decode(&value)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		// the list is omitted, so it isn't materialized in the query struct.
+		if value == nil {
+			continue
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,293 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+type streamOrder struct {
+	ID   string
+	Item struct {
+		Typename string `graphql:"__typename"`
+		Book     struct {
+			Title string
+		} `graphql:"... on Book"`
+		Movie struct {
+			Director string
+		} `graphql:"... on Movie"`
+	}
+}
+
+type streamOrdersQuery struct {
+	Orders struct {
+		Nodes    []streamOrder
+		PageInfo struct {
+			HasNextPage bool
+			EndCursor   string
+		}
+	} `graphql:"orders(first: $first)"`
+}
+
+func TestStreamQuery(t *testing.T) {
+	var query string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var payload struct {
+			Query string `json:"query"`
+		}
+		body, _ := io.ReadAll(req.Body)
+		_ = json.Unmarshal(body, &payload)
+		query = payload.Query
+
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"orders": {
+			"nodes": [
+				{"id": "1", "item": {"__typename": "Book", "title": "The Go Programming Language"}},
+				{"id": "2", "item": {"__typename": "Movie", "director": "Gopher"}},
+				{"id": "3", "item": {"__typename": "Book", "title": "Concurrency in Go"}}
+			],
+			"pageInfo": {"hasNextPage": true, "endCursor": "Mw=="}
+		}}}`)
+	})
+
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q streamOrdersQuery
+	var orders []streamOrder
+	err := graphql.StreamQuery(context.Background(), client, &q, "orders.nodes", map[string]any{
+		"first": 3,
+	}, func(order streamOrder) error {
+		orders = append(orders, order)
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(query, "nodes{id,item{__typename,... on Book{title},... on Movie{director}}}") {
+		t.Errorf("got query: %s, want the list fields", query)
+	}
+	if len(orders) != 3 {
+		t.Fatalf("got %d orders, want: 3", len(orders))
+	}
+	if orders[0].Item.Book.Title != "The Go Programming Language" || orders[1].Item.Movie.Director != "Gopher" ||
+		orders[1].Item.Book.Title != "" || orders[2].ID != "3" {
+		t.Errorf("got orders: %+v", orders)
+	}
+	if len(q.Orders.Nodes) != 0 {
+		t.Errorf("got %d nodes in the query struct, want: 0", len(q.Orders.Nodes))
+	}
+	if !q.Orders.PageInfo.HasNextPage || q.Orders.PageInfo.EndCursor != "Mw==" {
+		t.Errorf("got page info: %+v", q.Orders.PageInfo)
+	}
+}
+
+func TestStreamQuery_callbackError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"orders": {"nodes": [{"id": "1"}, {"id": "2"}, {"id": "3"}]}}}`)
+	})
+
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	errStop := errors.New("stop")
+	var q streamOrdersQuery
+	var ids []string
+	err := graphql.StreamQuery(context.Background(), client, &q, "orders.nodes", map[string]any{
+		"first": 3,
+	}, func(order streamOrder) error {
+		ids = append(ids, order.ID)
+		if len(ids) == 2 {
+			return errStop
+		}
+
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("got error: %v, want: stop", err)
+	}
+	if len(ids) != 2 {
+		t.Errorf("got ids: %v, want: [1 2]", ids)
+	}
+}
+
+func TestStreamQuery_errors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		path     string
+		response string
+		items    int
+		code     string
+	}{
+		{
+			name:     "graphql errors",
+			path:     "orders.nodes",
+			response: `{"data": {"orders": {"nodes": [{"id": "1"}]}}, "errors": [{"message": "partial"}]}`,
+			items:    1,
+		},
+		{
+			name:     "null data",
+			path:     "orders.nodes",
+			response: `{"errors": [{"message": "unauthorized"}], "data": null}`,
+		},
+		{
+			name:     "not a list",
+			path:     "orders.pageInfo",
+			response: `{"data": {"orders": {"pageInfo": {"hasNextPage": false}}}}`,
+			code:     graphql.ErrGraphQLDecode,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				mustWrite(w, tc.response)
+			})
+
+			client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+			var q streamOrdersQuery
+			items := 0
+			err := graphql.StreamQuery(context.Background(), client, &q, tc.path, map[string]any{
+				"first": 1,
+			}, func(order streamOrder) error {
+				items++
+
+				return nil
+			})
+
+			var errs graphql.Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("got error: %v, want: graphql errors", err)
+			}
+			if tc.code != "" && errs[0].Extensions["code"] != tc.code {
+				t.Errorf("got error: %v, want: %s", err, tc.code)
+			}
+			if items != tc.items {
+				t.Errorf("got %d items, want: %d", items, tc.items)
+			}
+		})
+	}
+
+	client := graphql.NewClient("/graphql", nil)
+	var q streamOrdersQuery
+	if err := graphql.StreamQuery(context.Background(), client, &q, "orders..nodes", nil, func(order streamOrder) error {
+		return nil
+	}); err == nil {
+		t.Error("got error: nil, want: invalid list path")
+	}
+}
+
+func TestStreamQuery_middlewareResponse(t *testing.T) {
+	requests := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"orders": {
+			"nodes": [{"id": "1"}, {"id": "2"}, {"id": "3"}],
+			"pageInfo": {"hasNextPage": false, "endCursor": "Mw=="}
+		}}}`)
+	})
+
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithResponseCache(nil, time.Minute),
+	)
+
+	variables := map[string]any{
+		"first": 3,
+	}
+
+	// the regular query fills the response cache.
+	var cached streamOrdersQuery
+	if err := client.Query(context.Background(), &cached, variables); err != nil {
+		t.Fatal(err)
+	}
+
+	// the cached data is delivered to the callback too, rather than materialized in the query struct.
+	var q streamOrdersQuery
+	var ids []string
+	err := graphql.StreamQuery(context.Background(), client, &q, "orders.nodes", variables, func(order streamOrder) error {
+		ids = append(ids, order.ID)
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests != 1 {
+		t.Errorf("got %d requests, want: 1", requests)
+	}
+	if len(ids) != 3 || ids[2] != "3" {
+		t.Errorf("got ids: %v, want: [1 2 3]", ids)
+	}
+	if len(q.Orders.Nodes) != 0 {
+		t.Errorf("got %d nodes in the query struct, want: 0", len(q.Orders.Nodes))
+	}
+	if q.Orders.PageInfo.EndCursor != "Mw==" {
+		t.Errorf("got page info: %+v", q.Orders.PageInfo)
+	}
+}
+
+func TestStreamQuery_retry(t *testing.T) {
+	attempts := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		attempts++
+		w.Header().Set("Content-Type", "application/json")
+		if attempts == 1 {
+			mustWrite(w, `{"data": {"orders": {"nodes": [], "tags": ["a", "b"]}}, "errors": [{"message": "unavailable"}]}`)
+
+			return
+		}
+
+		mustWrite(w, `{"data": {"orders": {"nodes": [{"id": "1"}], "tags": ["a", "b"]}}}`)
+	})
+
+	policy := graphql.NewBackoffRetryPolicy(1)
+	policy.BaseDelay = time.Millisecond
+	policy.RetryOnGraphQLError = func(errs graphql.Errors) bool {
+		return true
+	}
+
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRetryPolicy(policy),
+	)
+
+	var q struct {
+		Orders struct {
+			Nodes []streamOrder
+			Tags  []string
+		}
+	}
+	var ids []string
+	err := graphql.StreamQuery(context.Background(), client, &q, "orders.nodes", nil, func(order streamOrder) error {
+		ids = append(ids, order.ID)
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ids) != 1 || len(q.Orders.Tags) != 2 {
+		t.Errorf("got ids: %v, tags: %v", ids, q.Orders.Tags)
+	}
+}
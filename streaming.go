@@ -0,0 +1,219 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/hasura/go-graphql-client/pkg/jsonutil"
+)
+
+// responseReaderKey is the context key of the reader of the successful HTTP response.
+type responseReaderKey struct{}
+
+// responseReader reads the GraphQL response from the body of the successful HTTP response as it arrives,
+// instead of decoding the buffered body. The body is decompressed and closed by the client.
+type responseReader func(resp *http.Response, body io.Reader) *rawGraphQLResult
+
+// streamDecoder decodes the data value from the response stream.
+type streamDecoder func(dec *json.Decoder) error
+
+// WithStreamingDecode creates an option to decode the data of the response straight from the HTTP response body
+// into the query struct in one pass, regardless of where errors and extensions appear in the response.
+// The response isn't buffered, even in debug mode, so the peak memory of large responses is reduced.
+//
+// The data isn't available to middlewares, e.g. Response.Data is empty.
+// Operations are decoded as usual if the normalized cache is enabled, and queries if the query deduplication is enabled,
+// because they need the raw data.
+func WithStreamingDecode(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.streamingDecode = enabled
+	}
+}
+
+// withStreamTarget sets the struct where the data of the response stream is decoded into, if the streaming decode is enabled.
+// The data isn't streamed with the none error policy, because errors may follow the data in the response.
+func (c *Client) withStreamTarget(
+	ctx context.Context,
+	op operationType,
+	v any,
+	options *constructOptionsOutput,
+) context.Context {
+	if !c.streamingDecode || v == nil || c.normalizedCache != nil || (c.deduplicator != nil && op == queryOperation) ||
+		c.getErrorPolicy(options) == ErrorPolicyNone {
+		return ctx
+	}
+
+	decodeAttempt := newAttemptTarget(v)
+
+	return withStreamDecoder(ctx, func(dec *json.Decoder) error {
+		return decodeAttempt(func(target any) error {
+			return jsonutil.DecodeGraphQL(dec, target)
+		})
+	})
+}
+
+// newAttemptTarget returns the function which decodes every attempt into a copy of the initial value of v,
+// and then stores the result in v. The data of a retried attempt isn't merged into the data of the previous attempt.
+func newAttemptTarget(v any) func(decode func(target any) error) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return func(decode func(target any) error) error {
+			return decode(v)
+		}
+	}
+
+	initial := reflect.New(rv.Elem().Type()).Elem()
+	initial.Set(rv.Elem())
+
+	return func(decode func(target any) error) error {
+		target := reflect.New(initial.Type())
+		target.Elem().Set(initial)
+
+		err := decode(target.Interface())
+		rv.Elem().Set(target.Elem())
+
+		return err
+	}
+}
+
+// withStreamDecoder sets the decoder of the data of the response stream.
+func withStreamDecoder(ctx context.Context, decode streamDecoder) context.Context {
+	return withResponseReader(ctx, func(resp *http.Response, body io.Reader) *rawGraphQLResult {
+		return decodeStreamingResponse(body, decode)
+	})
+}
+
+// withResponseReader sets the reader of the successful HTTP response of the operation.
+// Operations with the reader aren't deduplicated or batched, because the response can't be shared.
+func withResponseReader(ctx context.Context, read responseReader) context.Context {
+	return context.WithValue(ctx, responseReaderKey{}, read)
+}
+
+// getResponseReader returns the reader of the successful HTTP response, nil if the response is decoded as usual.
+func getResponseReader(ctx context.Context) responseReader {
+	read, _ := ctx.Value(responseReaderKey{}).(responseReader)
+
+	return read
+}
+
+// decodeStreamingResponse decodes the response object key by key from the body.
+// The data is decoded by the stream decoder, errors and extensions are stored in the result.
+func decodeStreamingResponse(body io.Reader, decode streamDecoder) *rawGraphQLResult {
+	out := &rawGraphQLResult{}
+
+	if err := decodeStreamingObject(body, decode, out); err != nil {
+		out.Errors = append(out.Errors, newError(ErrJsonDecode, err))
+
+		return out
+	}
+
+	out.decoded = true
+
+	return out
+}
+
+func decodeStreamingObject(body io.Reader, decode streamDecoder, out *rawGraphQLResult) error {
+	peek := &valuePeekReader{r: body}
+	dec := json.NewDecoder(peek)
+	dec.UseNumber()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if tok != json.Delim('{') {
+		return fmt.Errorf("invalid response: expected an object, got %v", tok)
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch tok {
+		case "data":
+			peek.start(dec)
+
+			// the decoder can't continue after the data is partially decoded.
+			err := decode(dec)
+			out.streamedData = peek.first != 0 && peek.first != 'n'
+
+			if err != nil {
+				out.streamErr = err
+
+				return nil
+			}
+		case "errors":
+			// errors are decoded without json.Number, the same as the buffered response.
+			var errs json.RawMessage
+			if err := dec.Decode(&errs); err != nil {
+				return err
+			}
+
+			if err := json.Unmarshal(errs, &out.Errors); err != nil {
+				return err
+			}
+		case "extensions":
+			if err := dec.Decode(&out.Extensions); err != nil {
+				return err
+			}
+		default:
+			var skipped json.RawMessage
+			if err := dec.Decode(&skipped); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = dec.Token()
+
+	return err
+}
+
+// valuePeekReader finds the first byte of the next value of the decoder, so a null value is detected
+// while the value is decoded by the stream decoder. The byte may be buffered by the decoder already,
+// or be read from the body later.
+type valuePeekReader struct {
+	r io.Reader
+	// the first byte of the value, 0 while it isn't read yet
+	first   byte
+	peeking bool
+}
+
+func (p *valuePeekReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+
+	if p.peeking {
+		p.first = firstValueByte(b[:n])
+		p.peeking = p.first == 0
+	}
+
+	return n, err
+}
+
+// start looks for the first byte of the value which follows the key the decoder has just read.
+func (p *valuePeekReader) start(dec *json.Decoder) {
+	buffered, _ := io.ReadAll(dec.Buffered())
+	p.first = firstValueByte(buffered)
+	p.peeking = p.first == 0
+}
+
+// firstValueByte returns the first byte of the value after the colon of the key, 0 if there isn't any.
+func firstValueByte(b []byte) byte {
+	for _, c := range b {
+		switch c {
+		case ' ', '\t', '\r', '\n', ':':
+			continue
+		}
+
+		return c
+	}
+
+	return 0
+}
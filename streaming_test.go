@@ -0,0 +1,189 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+func TestClientOption_WithStreamingDecode(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// errors come before the data, unknown keys are skipped.
+		mustWrite(w, `{
+			"errors": [{"message": "partial", "extensions": {"code": "PARTIAL", "retryAfter": 1}}],
+			"hasNext": false,
+			"data": {"users": [{"name": "Gopher", "age": 13}, {"name": "Ferris", "age": 9}]},
+			"extensions": {"cost": 5}
+		}`)
+	})
+
+	var middlewareData []byte
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithStreamingDecode(true),
+		graphql.WithMiddleware(func(next graphql.Handler) graphql.Handler {
+			return func(ctx context.Context, req *graphql.Request) *graphql.Response {
+				resp := next(ctx, req)
+				middlewareData = resp.Data
+
+				return resp
+			}
+		}),
+	).WithDebug(true)
+
+	var q struct {
+		Users []struct {
+			Name string
+			Age  int
+		}
+	}
+	var extensions struct {
+		Cost int `json:"cost"`
+	}
+
+	err := client.Query(context.Background(), &q, nil, graphql.BindExtensions(&extensions))
+
+	var errs graphql.Errors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Message != "partial" {
+		t.Fatalf("got error: %v, want: partial", err)
+	}
+	// numbers of error extensions are decoded as float64, the same as buffered responses.
+	if retryAfter, ok := errs[0].Extensions["retryAfter"].(float64); !ok || retryAfter != 1 {
+		t.Errorf("got retryAfter: %#v, want: 1", errs[0].Extensions["retryAfter"])
+	}
+	if len(q.Users) != 2 || q.Users[0].Name != "Gopher" || q.Users[1].Age != 9 {
+		t.Errorf("got users: %+v", q.Users)
+	}
+	if extensions.Cost != 5 {
+		t.Errorf("got cost: %d, want: 5", extensions.Cost)
+	}
+	if len(middlewareData) != 0 {
+		t.Errorf("got middleware data: %s, want: empty", middlewareData)
+	}
+}
+
+func TestClientOption_WithStreamingDecode_decodeError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": 1}}, "errors": [{"message": "ignored"}]}`)
+	})
+
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithStreamingDecode(true),
+	)
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+
+	err := client.Query(context.Background(), &q, nil)
+
+	var errs graphql.Errors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Extensions["code"] != graphql.ErrGraphQLDecode {
+		t.Errorf("got error: %v, want: %s", err, graphql.ErrGraphQLDecode)
+	}
+}
+
+func TestClientOption_WithStreamingDecode_exec(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"extensions": {"tracing": {}}, "data": {"user": {"name": "Gopher"}}}`)
+	})
+
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithStreamingDecode(true),
+		graphql.WithNormalizedCache(graphql.NewNormalizedCache(nil)),
+	)
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+
+	// the normalized cache needs the raw data, the response is decoded as usual.
+	if err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+	if q.User.Name != "Gopher" {
+		t.Errorf("got name: %q, want: Gopher", q.User.Name)
+	}
+
+	var e struct {
+		User struct {
+			Name string
+		}
+	}
+	client = graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithStreamingDecode(true),
+	)
+	if err := client.Exec(context.Background(), "query { user { name } }", &e, nil); err != nil {
+		t.Fatal(err)
+	}
+	if e.User.Name != "Gopher" {
+		t.Errorf("got name: %q, want: Gopher", e.User.Name)
+	}
+}
+
+func TestClientOption_WithStreamingDecode_retry(t *testing.T) {
+	attempts := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		attempts++
+		w.Header().Set("Content-Type", "application/json")
+		if attempts == 1 {
+			mustWrite(w, `{"data": {"users": [{"name": "Gopher"}, {"name": null}]}, "errors": [{"message": "unavailable"}]}`)
+
+			return
+		}
+
+		mustWrite(w, `{"data": {"users": [{"name": "Gopher"}, {"name": "Ferris"}]}}`)
+	})
+
+	policy := graphql.NewBackoffRetryPolicy(1)
+	policy.BaseDelay = time.Millisecond
+	policy.RetryOnGraphQLError = func(errs graphql.Errors) bool {
+		return true
+	}
+
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithStreamingDecode(true),
+		graphql.WithRetryPolicy(policy),
+	)
+
+	var q struct {
+		Users []struct {
+			Name string
+		}
+	}
+
+	// every attempt is decoded into the initial value, rather than the data of the previous attempt.
+	if err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("got %d attempts, want: 2", attempts)
+	}
+	if len(q.Users) != 2 || q.Users[1].Name != "Ferris" {
+		t.Errorf("got users: %+v", q.Users)
+	}
+}
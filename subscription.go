@@ -17,6 +17,7 @@ import (
 	"github.com/coder/websocket"
 	"github.com/coder/websocket/wsjson"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // SubscriptionProtocolType represents the protocol specification enum of the subscription.
@@ -46,6 +47,21 @@ const (
 	// https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md
 	GraphQLWS SubscriptionProtocolType = "graphql-ws"
 
+	// GraphQLSSE enum implements the distinct connections mode of GraphQL over Server-Sent Events Protocol (graphql-sse).
+	// Every subscription opens its own event stream.
+	// https://github.com/enisdenjo/graphql-sse/blob/master/PROTOCOL.md
+	GraphQLSSE SubscriptionProtocolType = "graphql-sse"
+
+	// GraphQLSSESingleConnection enum implements the single connection mode of GraphQL over Server-Sent Events Protocol (graphql-sse).
+	// All subscriptions share an event stream which is reserved with a token.
+	// https://github.com/enisdenjo/graphql-sse/blob/master/PROTOCOL.md
+	GraphQLSSESingleConnection SubscriptionProtocolType = "graphql-sse-single-connection"
+
+	// MultipartHTTP enum implements the multipart HTTP protocol for GraphQL subscriptions of Apollo Router.
+	// Every subscription opens its own multipart/mixed response.
+	// https://www.apollographql.com/docs/graphos/routing/operations/subscriptions/multipart-protocol
+	MultipartHTTP SubscriptionProtocolType = "multipart-http"
+
 	// Receiving a message of a type or format which is not specified in this document
 	// The <error-message> can be vaguely descriptive on why the received message is invalid.
 	StatusInvalidMessage websocket.StatusCode = 4400
@@ -191,6 +207,8 @@ func (sc *SubscriptionContext) OnDisconnected() {
 
 // OnSubscriptionComplete executes the OnSubscriptionComplete callback if exists.
 func (sc *SubscriptionContext) OnSubscriptionComplete(subscription Subscription) {
+	subscription.endSpan(nil)
+
 	if sc.client != nil && sc.client.onSubscriptionComplete != nil {
 		sc.client.onSubscriptionComplete(subscription)
 	}
@@ -399,7 +417,12 @@ func (sc *SubscriptionContext) init(parentContext context.Context) error {
 	for {
 		ctx, cancel := context.WithCancel(parentContext)
 
-		conn, err := sc.client.createConn(ctx, sc.client.url, sc.client.websocketOptions)
+		// inject the trace context into the handshake headers.
+		options := sc.client.websocketOptions
+		span, header := sc.client.tracing.startConnection(ctx, options.HTTPHeader)
+		options.HTTPHeader = header
+
+		conn, err := sc.client.createConn(ctx, sc.client.url, options)
 		if err == nil {
 			conn.SetReadLimit(sc.client.readLimit)
 			// send connection init event to the server
@@ -415,6 +438,8 @@ func (sc *SubscriptionContext) init(parentContext context.Context) error {
 
 			err = sc.client.protocol.ConnectionInit(sc, connectionParams)
 			if err == nil {
+				endSpanWithError(span, nil)
+
 				sc.Context = ctx //nolint:fatcontext
 				sc.cancel = cancel
 
@@ -424,6 +449,7 @@ func (sc *SubscriptionContext) init(parentContext context.Context) error {
 			_ = conn.Close()
 		}
 
+		endSpanWithError(span, err)
 		cancel()
 
 		if errors.Is(err, context.Canceled) {
@@ -523,10 +549,14 @@ func (sc *SubscriptionContext) run() {
 
 			sc.setLastReceivedMessageAt(time.Now())
 			sub := sc.GetSubscription(message.ID)
-			if sub == nil {
+			// the subscription is found by its key if the ID is replaced,
+			// the messages of the replaced ID are from a previous attempt.
+			if sub == nil || sub.id != message.ID {
 				sub = &Subscription{}
 			}
 
+			sub.addMessageEvent(message)
+
 			execMessage := func() {
 				if err := sc.client.protocol.OnMessage(sc, *sub, message); err != nil {
 					sc.client.errorChan <- err
@@ -583,6 +613,13 @@ type Subscription struct {
 	payload GraphQLRequestPayload
 	handler func(data []byte, err error)
 	status  SubscriptionStatus
+	// persistedQuery indicates that the subscription sends the hash of the query only.
+	persistedQuery bool
+	// persistedQueryNotFound indicates that the server doesn't know the hash,
+	// so the full query is sent with the hash to register it.
+	persistedQueryNotFound bool
+	// the long-lived tracing span of the subscription
+	span trace.Span
 }
 
 // GetID returns the subscription ID.
@@ -600,6 +637,22 @@ func (s Subscription) GetPayload() GraphQLRequestPayload {
 	return s.payload
 }
 
+// getRequestPayload returns the payload which is sent to the server.
+// If the subscription uses automatic persisted queries, only the hash of the query is sent
+// until the server asks for the full query.
+func (s Subscription) getRequestPayload() GraphQLRequestPayload {
+	if s.persistedQuery {
+		payload := newPersistedQueryPayload(s.payload)
+		if s.persistedQueryNotFound {
+			payload.Query = s.payload.Query
+		}
+
+		return payload
+	}
+
+	return s.payload
+}
+
 // GetHandler a public getter for the subscription handler.
 func (s Subscription) GetHandler() func(data []byte, err error) {
 	return s.handler
@@ -618,11 +671,13 @@ func (s *Subscription) SetStatus(status SubscriptionStatus) {
 // The ID is newly generated to avoid subscription id conflict errors from the server.
 func (s Subscription) Clone() Subscription {
 	return Subscription{
-		id:      uuid.NewString(),
-		key:     s.key,
-		status:  SubscriptionWaiting,
-		payload: s.payload,
-		handler: s.handler,
+		id:             uuid.NewString(),
+		key:            s.key,
+		status:         SubscriptionWaiting,
+		payload:        s.payload,
+		handler:        s.handler,
+		persistedQuery: s.persistedQuery,
+		span:           s.span,
 	}
 }
 
@@ -636,6 +691,8 @@ type SubscriptionClient struct {
 	websocketOptions   WebsocketOptions
 	clientStatus       int32
 	createConn         CreateWebSocketConnFunc
+	// the connection is created by an HTTP streaming protocol instead of the WebSocket constructor
+	httpStream bool
 
 	readLimit                       int64 // max size of response message. Default 10 MB
 	retryTimeout                    time.Duration
@@ -646,6 +703,8 @@ type SubscriptionClient struct {
 
 	exitWhenNoSubscription bool
 	syncMode               bool
+	persistedQueries       bool
+	tracing                tracing
 	disabledLogTypes       []OperationMessageType
 	log                    func(args ...any)
 	retryStatusCodes       [][]int32
@@ -743,17 +802,33 @@ func (sc *SubscriptionClient) GetSubscription(id string) *Subscription {
 // In default, subscription client uses https://github.com/coder/websocket
 func (sc *SubscriptionClient) WithWebSocket(fn CreateWebSocketConnFunc) *SubscriptionClient {
 	sc.createConn = fn
+	sc.httpStream = false
 
 	return sc
 }
 
 // By default the subscription client uses the subscriptions-transport-ws protocol.
+// The graphql-sse and multipart HTTP protocols replace the WebSocket connection with HTTP requests,
+// the HTTP client and headers are configured by WithWebSocketOptions.
 func (sc *SubscriptionClient) WithProtocol(protocol SubscriptionProtocolType) *SubscriptionClient {
 	switch protocol {
 	case GraphQLWS:
 		sc.protocol = &graphqlWS{}
+		sc.resetWebSocketConn()
+	case GraphQLSSE, GraphQLSSESingleConnection:
+		sc.protocol = &graphqlWS{}
+		sc.createConn = newHTTPStreamConnFunc(sseDistinctConnections)
+		if protocol == GraphQLSSESingleConnection {
+			sc.createConn = newHTTPStreamConnFunc(sseSingleConnection)
+		}
+		sc.httpStream = true
+	case MultipartHTTP:
+		sc.protocol = &graphqlWS{}
+		sc.createConn = newHTTPStreamConnFunc(multipartSubscription)
+		sc.httpStream = true
 	case SubscriptionsTransportWS:
 		sc.protocol = &subscriptionsTransportWS{}
+		sc.resetWebSocketConn()
 	default:
 		panic(fmt.Sprintf("unknown subscription protocol %s", protocol))
 	}
@@ -763,6 +838,15 @@ func (sc *SubscriptionClient) WithProtocol(protocol SubscriptionProtocolType) *S
 	return sc
 }
 
+// resetWebSocketConn restores the WebSocket constructor if it was replaced by an HTTP streaming protocol.
+// The constructor of WithWebSocket is kept.
+func (sc *SubscriptionClient) resetWebSocketConn() {
+	if sc.httpStream {
+		sc.createConn = newWebsocketConn
+		sc.httpStream = false
+	}
+}
+
 // WithCustomProtocol changes the subscription protocol that implements the SubscriptionProtocol interface.
 func (sc *SubscriptionClient) WithCustomProtocol(
 	protocol SubscriptionProtocol,
@@ -871,6 +955,14 @@ func (sc *SubscriptionClient) WithSyncMode(value bool) *SubscriptionClient {
 	return sc
 }
 
+// WithAutomaticPersistedQueries sends the SHA-256 hash of the subscription query instead of the full document.
+// The full query is sent again if the server replies with PersistedQueryNotFound.
+func (sc *SubscriptionClient) WithAutomaticPersistedQueries(enabled bool) *SubscriptionClient {
+	sc.persistedQueries = enabled
+
+	return sc
+}
+
 // WithKeepAlive programs the websocket to ping on the specified interval.
 //
 // Deprecated: rename to WithWebSocketKeepAlive to avoid confusing with the keep-alive specification of the subscription protocol.
@@ -1059,7 +1151,9 @@ func (sc *SubscriptionClient) doRaw(
 			Variables:     variables,
 			OperationName: operationName,
 		},
-		handler: sc.wrapHandler(handler),
+		handler:        sc.wrapHandler(handler),
+		persistedQuery: sc.persistedQueries && query != "",
+		span:           sc.tracing.startSubscription(id, operationName),
 	}
 
 	sc.mutex.Lock()
@@ -1091,10 +1185,13 @@ func (sc *SubscriptionClient) wrapHandler(fn handlerFunc) func(data []byte, err
 
 // The input parameter is subscription ID that is returned from Subscribe function.
 func (sc *SubscriptionClient) Unsubscribe(id string) error {
-	if sc.getRawSubscription(id) == nil {
+	rawSub := sc.getRawSubscription(id)
+	if rawSub == nil {
 		return fmt.Errorf("%s: %w", id, ErrSubscriptionNotExists)
 	}
 
+	rawSub.endSpan(nil)
+
 	sc.mutex.Lock()
 	currentSession := sc.currentSession
 	delete(sc.rawSubscriptions, id)
@@ -1294,6 +1391,10 @@ func (sc *SubscriptionClient) GetWebSocketStatusCode(err error) websocket.Status
 
 // Close closes all subscription channel and websocket as well.
 func (sc *SubscriptionClient) Close() error {
+	for _, sub := range sc.getRawSubscriptions() {
+		sub.endSpan(nil)
+	}
+
 	return sc.close(sc.getCurrentSession())
 }
 
@@ -0,0 +1,546 @@
+package graphql
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// This file implements GraphQL over Server-Sent Events Protocol (graphql-sse)
+// https://github.com/enisdenjo/graphql-sse/blob/master/PROTOCOL.md
+//
+// The transport is an adapter of the WebsocketConn interface which translates messages
+// of the graphql-ws protocol into HTTP requests, so it reuses the life-cycle of the subscription client.
+// The adapter is shared with the multipart HTTP subscription protocol.
+
+const (
+	// the header of the reservation token in the single connection mode.
+	sseEventStreamTokenHeader = "X-GraphQL-Event-Stream-Token"
+
+	sseEventNext     = "next"
+	sseEventComplete = "complete"
+)
+
+// httpStreamMode represents the transport of the HTTP stream connection.
+type httpStreamMode int
+
+const (
+	// every operation opens its own event stream.
+	sseDistinctConnections httpStreamMode = iota
+	// all operations share an event stream which is reserved with a token.
+	sseSingleConnection
+	// every operation opens its own multipart/mixed response.
+	multipartSubscription
+)
+
+// httpStreamConn implements the WebsocketConn interface over HTTP requests and streamed responses.
+type httpStreamConn struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
+	url        string
+	httpClient *http.Client
+	header     http.Header
+	mode       httpStreamMode
+
+	messages  chan OperationMessage
+	errs      chan error
+	readLimit int64
+
+	mutex      sync.Mutex
+	token      string
+	operations map[string]context.CancelFunc
+}
+
+// newHTTPStreamConnFunc returns the function to create a HTTP stream connection.
+func newHTTPStreamConnFunc(mode httpStreamMode) CreateWebSocketConnFunc {
+	return func(ctx context.Context, endpoint string, options WebsocketOptions) (WebsocketConn, error) {
+		httpClient := options.HTTPClient
+		if httpClient == nil {
+			httpClient = http.DefaultClient
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+
+		return &httpStreamConn{
+			ctx:        ctx,
+			cancel:     cancel,
+			url:        toHTTPURL(endpoint),
+			httpClient: httpClient,
+			header:     options.HTTPHeader,
+			mode:       mode,
+			messages:   make(chan OperationMessage),
+			errs:       make(chan error, 1),
+			operations: make(map[string]context.CancelFunc),
+		}, nil
+	}
+}
+
+// toHTTPURL converts the WebSocket scheme of the endpoint to HTTP.
+func toHTTPURL(endpoint string) string {
+	switch {
+	case strings.HasPrefix(endpoint, "ws://"):
+		return "http://" + strings.TrimPrefix(endpoint, "ws://")
+	case strings.HasPrefix(endpoint, "wss://"):
+		return "https://" + strings.TrimPrefix(endpoint, "wss://")
+	default:
+		return endpoint
+	}
+}
+
+// ReadJSON waits for the next message of streamed responses.
+func (c *httpStreamConn) ReadJSON(v any) error {
+	select {
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	case err := <-c.errs:
+		return err
+	case msg := <-c.messages:
+		out, ok := v.(*OperationMessage)
+		if !ok {
+			return fmt.Errorf("unsupported message type %T", v)
+		}
+
+		*out = msg
+
+		return nil
+	}
+}
+
+// WriteJSON translates the graphql-ws message into the HTTP request.
+func (c *httpStreamConn) WriteJSON(v any) error {
+	msg, ok := v.(OperationMessage)
+	if !ok {
+		return fmt.Errorf("unsupported message type %T", v)
+	}
+
+	switch msg.Type {
+	case GQLConnectionInit:
+		if c.mode != sseSingleConnection {
+			// every operation opens its own stream, there is no handshake.
+			go c.push(OperationMessage{Type: GQLConnectionAck})
+
+			return nil
+		}
+
+		return c.reserve()
+	case GQLSubscribe:
+		if c.mode == sseSingleConnection {
+			return c.subscribeSingleConnection(msg)
+		}
+
+		c.subscribeDistinct(msg)
+
+		return nil
+	case GQLComplete:
+		return c.unsubscribe(msg.ID)
+	default:
+		return nil
+	}
+}
+
+// Ping is a no-op because HTTP streams don't support ping frames.
+func (c *httpStreamConn) Ping() error {
+	return nil
+}
+
+// Close cancels all streams.
+func (c *httpStreamConn) Close() error {
+	c.cancel()
+
+	return nil
+}
+
+// SetReadLimit sets the maximum size in bytes of an event line.
+func (c *httpStreamConn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// GetCloseStatus returns -1 because HTTP streams don't have close status codes.
+func (c *httpStreamConn) GetCloseStatus(err error) int32 {
+	return -1
+}
+
+// push sends the message to the reader, or drops it if the connection was closed.
+func (c *httpStreamConn) push(msg OperationMessage) {
+	select {
+	case c.messages <- msg:
+	case <-c.ctx.Done():
+	}
+}
+
+// fail reports the connection error to the reader, so the subscription client restarts the session.
+func (c *httpStreamConn) fail(err error) {
+	if c.ctx.Err() != nil {
+		return
+	}
+
+	select {
+	case c.errs <- err:
+	default:
+	}
+}
+
+func (c *httpStreamConn) newRequest(
+	ctx context.Context,
+	method string,
+	requestURL string,
+	body io.Reader,
+) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range c.header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+
+	c.mutex.Lock()
+	token := c.token
+	c.mutex.Unlock()
+
+	if token != "" {
+		req.Header.Set(sseEventStreamTokenHeader, token)
+	}
+
+	return req, nil
+}
+
+// subscribeDistinct opens a new stream for the operation.
+func (c *httpStreamConn) subscribeDistinct(msg OperationMessage) {
+	ctx, cancel := context.WithCancel(c.ctx)
+
+	c.mutex.Lock()
+	c.operations[msg.ID] = cancel
+	c.mutex.Unlock()
+
+	go func() {
+		defer c.removeOperation(msg.ID)
+
+		resp, err := c.openEventStream(ctx, http.MethodPost, bytes.NewReader(msg.Payload))
+		if err != nil {
+			if ctx.Err() == nil {
+				c.fail(fmt.Errorf("%w: %w", io.EOF, err))
+			}
+
+			return
+		}
+
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+
+		switch {
+		case c.mode == multipartSubscription && isMultipartSubscription(resp):
+			err = c.readMultipartSubscription(msg.ID, resp)
+		case c.mode != multipartSubscription && isEventStream(resp):
+			err = c.readEvents(resp.Body, func(event string, data []byte) bool {
+				switch event {
+				case sseEventNext:
+					c.push(OperationMessage{ID: msg.ID, Type: GQLNext, Payload: data})
+				case sseEventComplete:
+					c.push(OperationMessage{ID: msg.ID, Type: GQLComplete})
+
+					return false
+				}
+
+				return true
+			})
+		default:
+			// the server replies the result or errors of the operation in a single JSON response.
+			c.pushResult(msg.ID, resp)
+
+			return
+		}
+
+		if err != nil && ctx.Err() == nil {
+			c.fail(err)
+		}
+	}()
+}
+
+// reserve requests the reservation token and opens the event stream of the single connection mode.
+func (c *httpStreamConn) reserve() error {
+	req, err := c.newRequest(c.ctx, http.MethodPut, c.url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return NetworkError{statusCode: resp.StatusCode, body: string(body)}
+	}
+
+	c.mutex.Lock()
+	c.token = string(bytes.TrimSpace(body))
+	c.mutex.Unlock()
+
+	go func() {
+		resp, err := c.openEventStream(c.ctx, http.MethodGet, nil)
+		if err != nil {
+			c.fail(fmt.Errorf("%w: %w", io.EOF, err))
+
+			return
+		}
+
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+
+		if !isEventStream(resp) {
+			c.fail(fmt.Errorf("%w: unexpected content type of the event stream %s", io.EOF, resp.Header.Get("Content-Type")))
+
+			return
+		}
+
+		c.push(OperationMessage{Type: GQLConnectionAck})
+
+		err = c.readEvents(resp.Body, func(event string, data []byte) bool {
+			var payload struct {
+				ID      string          `json:"id"`
+				Payload json.RawMessage `json:"payload"`
+			}
+
+			if err := json.Unmarshal(data, &payload); err != nil {
+				return true
+			}
+
+			switch event {
+			case sseEventNext:
+				c.push(OperationMessage{ID: payload.ID, Type: GQLNext, Payload: payload.Payload})
+			case sseEventComplete:
+				c.removeOperation(payload.ID)
+				c.push(OperationMessage{ID: payload.ID, Type: GQLComplete})
+			}
+
+			return true
+		})
+		if err == nil {
+			// the server closed the event stream, reconnect to resume subscriptions.
+			err = io.EOF
+		}
+
+		c.fail(err)
+	}()
+
+	return nil
+}
+
+// subscribeSingleConnection requests the operation whose results are streamed through the reserved event stream.
+func (c *httpStreamConn) subscribeSingleConnection(msg OperationMessage) error {
+	var payload map[string]any
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return err
+	}
+
+	extensions, _ := payload["extensions"].(map[string]any)
+	if extensions == nil {
+		extensions = make(map[string]any)
+	}
+
+	extensions["operationId"] = msg.ID
+	payload["extensions"] = extensions
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := c.newRequest(c.ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode == http.StatusAccepted {
+		c.mutex.Lock()
+		c.operations[msg.ID] = func() {}
+		c.mutex.Unlock()
+
+		return nil
+	}
+
+	// the operation is rejected, e.g. validation errors.
+	go c.pushResult(msg.ID, resp)
+
+	return nil
+}
+
+// unsubscribe stops the operation.
+func (c *httpStreamConn) unsubscribe(id string) error {
+	c.mutex.Lock()
+	cancel, ok := c.operations[id]
+	delete(c.operations, id)
+	c.mutex.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	cancel()
+
+	if c.mode != sseSingleConnection {
+		return nil
+	}
+
+	req, err := c.newRequest(c.ctx, http.MethodDelete, c.url, nil)
+	if err != nil {
+		return err
+	}
+
+	query := req.URL.Query()
+	query.Set("operationId", id)
+	req.URL.RawQuery = query.Encode()
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	_ = resp.Body.Close()
+
+	return nil
+}
+
+func (c *httpStreamConn) removeOperation(id string) {
+	c.mutex.Lock()
+	delete(c.operations, id)
+	c.mutex.Unlock()
+}
+
+func (c *httpStreamConn) openEventStream(ctx context.Context, method string, body io.Reader) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, c.url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.mode == multipartSubscription {
+		req.Header.Set("Accept", multipartSubscriptionAcceptHeader)
+	} else {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// pushResult converts the JSON response of the operation into messages.
+func (c *httpStreamConn) pushResult(id string, resp *http.Response) {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.fail(fmt.Errorf("%w: %w", io.EOF, err))
+
+		return
+	}
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors Errors          `json:"errors"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil || (len(result.Data) == 0 && len(result.Errors) == 0) {
+		result.Errors = Errors{newError(ErrRequestError, NetworkError{
+			statusCode: resp.StatusCode,
+			body:       string(body),
+		})}
+	}
+
+	if len(result.Data) == 0 || resp.StatusCode >= http.StatusBadRequest {
+		payload, _ := json.Marshal(result.Errors)
+		c.push(OperationMessage{ID: id, Type: GQLError, Payload: payload})
+
+		return
+	}
+
+	c.push(OperationMessage{ID: id, Type: GQLNext, Payload: body})
+	c.push(OperationMessage{ID: id, Type: GQLComplete})
+}
+
+// readEvents parses the event stream and calls the callback for every event until it returns false.
+// https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+func (c *httpStreamConn) readEvents(r io.Reader, callback func(event string, data []byte) bool) error {
+	scanner := bufio.NewScanner(r)
+	if c.readLimit > 0 {
+		scanner.Buffer(make([]byte, 0, 4096), int(c.readLimit))
+	}
+
+	var event string
+	var data bytes.Buffer
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" {
+			if data.Len() > 0 || event != "" {
+				if !callback(event, bytes.Clone(data.Bytes())) {
+					return nil
+				}
+			}
+
+			event = ""
+			data.Reset()
+
+			continue
+		}
+
+		// lines starting with a colon are comments which keep the connection alive.
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+
+			data.WriteString(value)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: %w", io.EOF, err)
+	}
+
+	return io.EOF
+}
+
+func isEventStream(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusOK &&
+		strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+}
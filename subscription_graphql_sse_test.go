@@ -0,0 +1,211 @@
+package graphql_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+type helloSaidSubscription struct {
+	HelloSaid struct {
+		Msg string
+	}
+}
+
+func TestSubscription_graphqlSSE(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Accept") != "text/event-stream" {
+			t.Errorf("got %s request with Accept: %s", r.Method, r.Header.Get("Accept"))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("got Authorization: %q, want: Bearer token", got)
+		}
+		var payload graphql.GraphQLRequestPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+			return
+		}
+		if got, want := payload.Query, "subscription{helloSaid{msg}}"; got != want {
+			t.Errorf("got query: %q, want: %q", got, want)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 2; i++ {
+			mustWrite(w, fmt.Sprintf("event: next\ndata: {\"data\": {\"helloSaid\": {\"msg\": \"hello %d\"}}}\n\n", i))
+			w.(http.Flusher).Flush()
+		}
+		mustWrite(w, ":\n\nevent: complete\ndata:\n\n")
+	}))
+	defer server.Close()
+
+	client := graphql.NewSubscriptionClient(server.URL).
+		WithProtocol(graphql.GraphQLSSE).
+		WithWebSocketOptions(graphql.WebsocketOptions{
+			HTTPHeader: http.Header{"Authorization": []string{"Bearer token"}},
+		}).
+		WithSyncMode(true)
+
+	var messages []string
+	_, err := client.Subscribe(&helloSaidSubscription{}, nil, func(data []byte, err error) error {
+		if err != nil {
+			t.Errorf("got error: %s, want: nil", err)
+			return nil
+		}
+		var sub helloSaidSubscription
+		if err := graphql.UnmarshalGraphQL(data, &sub); err != nil {
+			t.Error(err)
+		}
+		messages = append(messages, sub.HelloSaid.Msg)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Run()
+	}()
+
+	// the client exits when the server completes the only subscription.
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription timeout")
+	}
+
+	if len(messages) != 2 || messages[0] != "hello 0" || messages[1] != "hello 1" {
+		t.Errorf("got messages: %v, want: [hello 0 hello 1]", messages)
+	}
+}
+
+func TestSubscription_graphqlSSESingleConnection(t *testing.T) {
+	const token = "reservation-token"
+	operationIDs := make(chan string, 1)
+	deletedIDs := make(chan string, 1)
+	var mu sync.Mutex
+	var subscribedID string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut && r.Header.Get("X-GraphQL-Event-Stream-Token") != token {
+			t.Errorf("got %s request without the reservation token", r.Method)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPut:
+			w.WriteHeader(http.StatusCreated)
+			mustWrite(w, token)
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+
+			select {
+			case id := <-operationIDs:
+				mustWrite(w, fmt.Sprintf("event: next\ndata: {\"id\": %q, \"payload\": {\"data\": {\"helloSaid\": {\"msg\": \"hello\"}}}}\n\n", id))
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+			<-r.Context().Done()
+		case http.MethodPost:
+			var payload struct {
+				Query      string `json:"query"`
+				Extensions struct {
+					OperationID string `json:"operationId"`
+				} `json:"extensions"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				t.Error(err)
+				return
+			}
+			if payload.Extensions.OperationID == "" {
+				t.Error("got empty operationId")
+			}
+			mu.Lock()
+			subscribedID = payload.Extensions.OperationID
+			mu.Unlock()
+			w.WriteHeader(http.StatusAccepted)
+			operationIDs <- payload.Extensions.OperationID
+		case http.MethodDelete:
+			deletedIDs <- r.URL.Query().Get("operationId")
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	client := graphql.NewSubscriptionClient(server.URL).
+		WithProtocol(graphql.GraphQLSSESingleConnection).
+		WithSyncMode(true)
+
+	msgChan := make(chan string, 1)
+	var once sync.Once
+	id, err := client.Subscribe(&helloSaidSubscription{}, nil, func(data []byte, err error) error {
+		if err != nil {
+			t.Errorf("got error: %s, want: nil", err)
+			return nil
+		}
+		var sub helloSaidSubscription
+		if err := graphql.UnmarshalGraphQL(data, &sub); err != nil {
+			t.Error(err)
+		}
+		once.Do(func() {
+			msgChan <- sub.HelloSaid.Msg
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Run()
+	}()
+
+	select {
+	case msg := <-msgChan:
+		if msg != "hello" {
+			t.Errorf("got message: %s, want: hello", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription timeout")
+	}
+
+	if err := client.Unsubscribe(id); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case deletedID := <-deletedIDs:
+		mu.Lock()
+		if deletedID != subscribedID {
+			t.Errorf("got deleted operation id: %s, want: %s", deletedID, subscribedID)
+		}
+		mu.Unlock()
+	case <-time.After(5 * time.Second):
+		t.Fatal("unsubscribe timeout")
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("client close timeout")
+	}
+}
@@ -50,7 +50,7 @@ func (gws *graphqlWS) Subscribe(ctx *SubscriptionContext, sub Subscription) erro
 		return nil
 	}
 
-	payload, err := json.Marshal(sub.GetPayload())
+	payload, err := json.Marshal(sub.getRequestPayload())
 	if err != nil {
 		return err
 	}
@@ -103,6 +103,10 @@ func (gws *graphqlWS) OnMessage(
 			return nil //nolint:nilerr
 		}
 
+		if ctx.retryPersistedQuery(subscription, errs) {
+			return nil
+		}
+
 		if len(errs) > 0 {
 			subscription.handler(nil, errs)
 
@@ -128,6 +132,10 @@ func (gws *graphqlWS) OnMessage(
 			return nil
 		}
 
+		if ctx.retryPersistedQuery(subscription, out.Errors) {
+			return nil
+		}
+
 		if len(out.Errors) > 0 {
 			subscription.handler(nil, out.Errors)
 
@@ -145,11 +153,15 @@ func (gws *graphqlWS) OnMessage(
 			"source": "server",
 		}, message.Type)
 		sub := ctx.GetSubscription(message.ID)
-		if sub == nil {
+
+		switch {
+		case sub == nil:
 			ctx.OnSubscriptionComplete(Subscription{
 				id: message.ID,
 			})
-		} else {
+		case sub.id != message.ID:
+			// the previous attempt of a subscription which is resent under a new ID.
+		default:
 			ctx.OnSubscriptionComplete(*sub)
 			ctx.SetSubscription(sub.GetKey(), nil)
 		}
@@ -0,0 +1,97 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+)
+
+// This file implements the multipart HTTP protocol for GraphQL subscriptions of Apollo Router.
+// https://www.apollographql.com/docs/graphos/routing/operations/subscriptions/multipart-protocol
+//
+// The server replies a multipart/mixed response for every subscription. Each part is a JSON object:
+//   - {} is a heartbeat to keep the connection alive.
+//   - {"payload": {"data": ..., "errors": ...}} is the result of the subscription.
+//   - {"payload": null, "errors": [...]} is a fatal transport error, the server closes the response after it.
+
+const multipartSubscriptionAcceptHeader = `multipart/mixed;subscriptionSpec="1.0", application/json`
+
+// multipartSubscriptionMessage is a part of the multipart subscription response.
+type multipartSubscriptionMessage struct {
+	Payload json.RawMessage `json:"payload"`
+	Errors  Errors          `json:"errors"`
+}
+
+func isMultipartSubscription(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+
+	return err == nil && mediaType == "multipart/mixed"
+}
+
+// readMultipartSubscription reads parts of the response until the closing boundary,
+// then completes the subscription.
+func (c *httpStreamConn) readMultipartSubscription(id string, resp *http.Response) error {
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return fmt.Errorf("%w: %w", io.EOF, err)
+	}
+
+	boundary := params["boundary"]
+	if boundary == "" {
+		boundary = "-"
+	}
+
+	reader := multipart.NewReader(resp.Body, boundary)
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			c.push(OperationMessage{ID: id, Type: GQLComplete})
+
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("%w: %w", io.EOF, err)
+		}
+
+		var partReader io.Reader = part
+		if c.readLimit > 0 {
+			partReader = io.LimitReader(part, c.readLimit)
+		}
+
+		var message multipartSubscriptionMessage
+
+		err = json.NewDecoder(partReader).Decode(&message)
+		if errors.Is(err, io.EOF) {
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("%w: %w", io.EOF, err)
+		}
+
+		switch {
+		case len(message.Errors) > 0:
+			payload, err := json.Marshal(message.Errors)
+			if err != nil {
+				return err
+			}
+
+			c.push(OperationMessage{ID: id, Type: GQLError, Payload: payload})
+		case len(message.Payload) == 0 || string(message.Payload) == "null":
+			// heartbeat
+			c.push(OperationMessage{ID: id, Type: GQLPing})
+		default:
+			c.push(OperationMessage{ID: id, Type: GQLNext, Payload: message.Payload})
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package graphql_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/hasura/go-graphql-client"
+)
+
+func newMultipartSubscriptionServer(t *testing.T, parts []string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("Accept"), `multipart/mixed;subscriptionSpec="1.0", application/json`; got != want {
+			t.Errorf("got Accept: %q, want: %q", got, want)
+		}
+
+		w.Header().Set("Content-Type", `multipart/mixed;boundary="graphql";subscriptionSpec="1.0"`)
+		for _, part := range parts {
+			mustWrite(w, "\r\n--graphql\r\ncontent-type: application/json\r\n\r\n"+part)
+			w.(http.Flusher).Flush()
+		}
+		mustWrite(w, "\r\n--graphql--\r\n")
+	}))
+}
+
+func TestSubscription_multipartHTTP(t *testing.T) {
+	server := newMultipartSubscriptionServer(t, []string{
+		`{}`,
+		`{"payload": {"data": {"helloSaid": {"msg": "hello 0"}}}}`,
+		`{}`,
+		`{"payload": {"data": {"helloSaid": {"msg": "hello 1"}}}}`,
+	})
+	defer server.Close()
+
+	var heartbeats int32
+	client := graphql.NewSubscriptionClient(server.URL).
+		WithProtocol(graphql.MultipartHTTP).
+		WithSyncMode(true).
+		OnConnectionAlive(func() {
+			atomic.AddInt32(&heartbeats, 1)
+		})
+
+	var messages []string
+	_, err := client.Subscribe(&helloSaidSubscription{}, nil, func(data []byte, err error) error {
+		if err != nil {
+			t.Errorf("got error: %s, want: nil", err)
+			return nil
+		}
+		var sub helloSaidSubscription
+		if err := graphql.UnmarshalGraphQL(data, &sub); err != nil {
+			t.Error(err)
+		}
+		messages = append(messages, sub.HelloSaid.Msg)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Run()
+	}()
+
+	// the subscription is completed by the closing boundary, then the client exits.
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription timeout")
+	}
+
+	if len(messages) != 2 || messages[0] != "hello 0" || messages[1] != "hello 1" {
+		t.Errorf("got messages: %v, want: [hello 0 hello 1]", messages)
+	}
+	if got := atomic.LoadInt32(&heartbeats); got != 2 {
+		t.Errorf("got %d heartbeats, want: 2", got)
+	}
+}
+
+func TestSubscription_multipartHTTP_transportError(t *testing.T) {
+	server := newMultipartSubscriptionServer(t, []string{
+		`{"payload": null, "errors": [{"message": "subscription source closed", "extensions": {"code": "SUBSCRIPTION_ERROR"}}]}`,
+	})
+	defer server.Close()
+
+	client := graphql.NewSubscriptionClient(server.URL).
+		WithProtocol(graphql.MultipartHTTP).
+		WithSyncMode(true)
+
+	var handlerErr error
+	_, err := client.Subscribe(&helloSaidSubscription{}, nil, func(data []byte, err error) error {
+		handlerErr = err
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Run()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription timeout")
+	}
+
+	var errs graphql.Errors
+	if !errors.As(handlerErr, &errs) || len(errs) != 1 || errs[0].Message != "subscription source closed" {
+		t.Errorf("got error: %v, want: subscription source closed", handlerErr)
+	}
+}
+
+func TestSubscriptionClient_WithProtocol_webSocket(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+			Subprotocols: []string{"graphql-transport-ws"},
+		})
+		if err != nil {
+			t.Errorf("got a request which isn't a WebSocket connection: %s", err)
+			return
+		}
+		defer conn.CloseNow()
+
+		ctx := r.Context()
+		for {
+			var msg graphql.OperationMessage
+			if err := wsjson.Read(ctx, conn, &msg); err != nil {
+				return
+			}
+
+			switch msg.Type {
+			case graphql.GQLConnectionInit:
+				_ = wsjson.Write(ctx, conn, graphql.OperationMessage{Type: graphql.GQLConnectionAck})
+			case graphql.GQLSubscribe:
+				_ = wsjson.Write(ctx, conn, graphql.OperationMessage{
+					ID:      msg.ID,
+					Type:    graphql.GQLNext,
+					Payload: json.RawMessage(`{"data": {"helloSaid": {"msg": "hello"}}}`),
+				})
+				_ = wsjson.Write(ctx, conn, graphql.OperationMessage{ID: msg.ID, Type: graphql.GQLComplete})
+			}
+		}
+	}))
+	defer server.Close()
+
+	// switching back to a WebSocket protocol restores the WebSocket connection.
+	client := graphql.NewSubscriptionClient(strings.Replace(server.URL, "http", "ws", 1)).
+		WithProtocol(graphql.MultipartHTTP).
+		WithProtocol(graphql.GraphQLWS).
+		WithSyncMode(true)
+
+	var sub struct {
+		HelloSaid struct {
+			Msg string
+		}
+	}
+
+	msgChan := make(chan string, 1)
+	_, err := client.Subscribe(&sub, nil, func(data []byte, err error) error {
+		if err != nil {
+			t.Errorf("got error: %s, want: nil", err)
+			return nil
+		}
+		if err := graphql.UnmarshalGraphQL(data, &sub); err != nil {
+			t.Error(err)
+		}
+		msgChan <- sub.HelloSaid.Msg
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		_ = client.Run()
+	}()
+	defer client.Close()
+
+	select {
+	case msg := <-msgChan:
+		if msg != "hello" {
+			t.Errorf("got message: %s, want: hello", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription timeout")
+	}
+}
@@ -67,7 +67,7 @@ func (stw *subscriptionsTransportWS) Subscribe(ctx *SubscriptionContext, sub Sub
 		return nil
 	}
 
-	payload, err := json.Marshal(sub.GetPayload())
+	payload, err := json.Marshal(sub.getRequestPayload())
 	if err != nil {
 		return err
 	}
@@ -120,6 +120,10 @@ func (stw *subscriptionsTransportWS) OnMessage(
 			return nil //nolint:nilerr
 		}
 
+		if ctx.retryPersistedQuery(subscription, errs) {
+			return nil
+		}
+
 		if len(errs) > 0 {
 			subscription.handler(nil, errs)
 
@@ -145,6 +149,10 @@ func (stw *subscriptionsTransportWS) OnMessage(
 			return nil
 		}
 
+		if ctx.retryPersistedQuery(subscription, out.Errors) {
+			return nil
+		}
+
 		if len(out.Errors) > 0 {
 			subscription.handler(nil, out.Errors)
 
@@ -197,11 +205,14 @@ func (stw *subscriptionsTransportWS) OnMessage(
 		}, GQLComplete)
 		sub := ctx.GetSubscription(message.ID)
 
-		if sub == nil {
+		switch {
+		case sub == nil:
 			ctx.OnSubscriptionComplete(Subscription{
 				id: message.ID,
 			})
-		} else {
+		case sub.id != message.ID:
+			// the previous attempt of a subscription which is resent under a new ID.
+		default:
 			ctx.OnSubscriptionComplete(*sub)
 			ctx.SetSubscription(sub.GetKey(), nil)
 		}
@@ -0,0 +1,233 @@
+package graphql
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OpenTelemetry tracing of operations. The global tracer provider and propagator are used by default,
+// so tracing is a no-op until the application configures OpenTelemetry.
+// https://opentelemetry.io/docs/specs/semconv/graphql/graphql-spans/
+
+const tracerName = "github.com/hasura/go-graphql-client"
+
+// attribute keys of spans.
+const (
+	attrOperationType  = attribute.Key("graphql.operation.type")
+	attrOperationName  = attribute.Key("graphql.operation.name")
+	attrAttempts       = attribute.Key("graphql.client.attempts")
+	attrAttempt        = attribute.Key("graphql.client.attempt")
+	attrSubscriptionID = attribute.Key("graphql.subscription.id")
+	attrMessageType    = attribute.Key("graphql.message.type")
+	attrHTTPMethod     = attribute.Key("http.request.method")
+	attrHTTPStatusCode = attribute.Key("http.response.status_code")
+	attrURL            = attribute.Key("url.full")
+)
+
+// operationSpanKey is the context key of the operation span, so HTTP attempts can record the attempt count.
+type operationSpanKey struct{}
+
+// tracing holds the OpenTelemetry settings of the client.
+type tracing struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+}
+
+func (t tracing) tracer() trace.Tracer {
+	provider := t.provider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	return provider.Tracer(tracerName)
+}
+
+func (t tracing) textMapPropagator() propagation.TextMapPropagator {
+	if t.propagator == nil {
+		return otel.GetTextMapPropagator()
+	}
+
+	return t.propagator
+}
+
+// inject writes the trace context of ctx into the HTTP headers.
+func (t tracing) inject(ctx context.Context, header http.Header) {
+	t.textMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// startOperation starts the span of the GraphQL operation.
+func (t tracing) startOperation(
+	ctx context.Context,
+	op OperationType,
+	operationName string,
+) (context.Context, trace.Span) {
+	spanName := string(op)
+	if operationName != "" {
+		spanName += " " + operationName
+	}
+
+	attrs := []attribute.KeyValue{attrOperationType.String(string(op))}
+	if operationName != "" {
+		attrs = append(attrs, attrOperationName.String(operationName))
+	}
+
+	ctx, span := t.tracer().Start(
+		ctx,
+		spanName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+
+	return context.WithValue(ctx, operationSpanKey{}, span), span
+}
+
+// setAttempts records the attempt count on the operation span of the context if exists,
+// or of every caller of the shared request.
+func setAttempts(ctx context.Context, attempts int) {
+	if callers := requestCallers(ctx); callers != nil {
+		for _, caller := range callers {
+			setAttempts(caller, attempts)
+		}
+
+		return
+	}
+
+	if span, ok := ctx.Value(operationSpanKey{}).(trace.Span); ok {
+		span.SetAttributes(attrAttempts.Int(attempts))
+	}
+}
+
+// startAttempt starts the span of the HTTP request attempt. The attempt number starts from 1.
+// The span of the shared request links to the operation spans of the callers.
+func (t tracing) startAttempt(
+	ctx context.Context,
+	method string,
+	url string,
+	attempt int,
+) (context.Context, trace.Span) {
+	var links []trace.Link
+
+	for _, caller := range requestCallers(ctx) {
+		if link := trace.LinkFromContext(caller); link.SpanContext.IsValid() {
+			links = append(links, link)
+		}
+	}
+
+	return t.tracer().Start(
+		ctx,
+		"HTTP "+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attrHTTPMethod.String(method),
+			attrURL.String(url),
+			attrAttempt.Int(attempt),
+		),
+		trace.WithLinks(links...),
+	)
+}
+
+// endAttemptSpan records the response status and errors of the attempt and ends the span.
+func endAttemptSpan(span trace.Span, resp *http.Response, errs Errors) {
+	if resp != nil {
+		span.SetAttributes(attrHTTPStatusCode.Int(resp.StatusCode))
+	}
+
+	endSpan(span, errs)
+}
+
+// startConnection starts the span of the connection handshake,
+// and returns a copy of the headers with the trace context.
+func (t tracing) startConnection(ctx context.Context, header http.Header) (trace.Span, http.Header) {
+	ctx, span := t.tracer().Start(ctx, "subscription connect", trace.WithSpanKind(trace.SpanKindClient))
+
+	header = header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	t.inject(ctx, header)
+
+	return span, header
+}
+
+// startSubscription starts the long-lived span of the subscription.
+func (t tracing) startSubscription(id string, operationName string) trace.Span {
+	_, span := t.startOperation(context.Background(), OperationTypeSubscription, operationName)
+	span.SetAttributes(attrSubscriptionID.String(id))
+
+	return span
+}
+
+// addMessageEvent records the received message on the subscription span.
+func (s Subscription) addMessageEvent(message OperationMessage) {
+	if s.span != nil {
+		s.span.AddEvent("message", trace.WithAttributes(attrMessageType.String(string(message.Type))))
+	}
+}
+
+// endSpan ends the span of the subscription if exists.
+func (s Subscription) endSpan(err error) {
+	if s.span != nil {
+		endSpanWithError(s.span, err)
+	}
+}
+
+// endSpanWithError records the error and ends the span.
+func endSpanWithError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// endSpan records errors of the operation and ends the span.
+func endSpan(span trace.Span, errs Errors) {
+	if len(errs) > 0 {
+		span.RecordError(errs)
+		span.SetStatus(codes.Error, errs[0].Message)
+	}
+
+	span.End()
+}
+
+// WithTracerProvider creates an option to set the OpenTelemetry tracer provider.
+// The global tracer provider is used by default.
+func WithTracerProvider(provider trace.TracerProvider) ClientOption {
+	return func(c *Client) {
+		c.tracing.provider = provider
+	}
+}
+
+// WithTextMapPropagator creates an option to set the OpenTelemetry propagator
+// which injects the trace context into HTTP headers. The global propagator is used by default.
+func WithTextMapPropagator(propagator propagation.TextMapPropagator) ClientOption {
+	return func(c *Client) {
+		c.tracing.propagator = propagator
+	}
+}
+
+// WithTracerProvider sets the OpenTelemetry tracer provider of subscriptions.
+// The global tracer provider is used by default.
+func (sc *SubscriptionClient) WithTracerProvider(provider trace.TracerProvider) *SubscriptionClient {
+	sc.tracing.provider = provider
+
+	return sc
+}
+
+// WithTextMapPropagator sets the OpenTelemetry propagator which injects the trace context into
+// the headers of the WebSocket handshake. The global propagator is used by default.
+func (sc *SubscriptionClient) WithTextMapPropagator(
+	propagator propagation.TextMapPropagator,
+) *SubscriptionClient {
+	sc.tracing.propagator = propagator
+
+	return sc
+}
@@ -0,0 +1,204 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hasura/go-graphql-client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+
+	return attribute.Value{}
+}
+
+func TestClientOption_WithTracerProvider(t *testing.T) {
+	var attempts int32
+	var traceparents []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		traceparents = append(traceparents, req.Header.Get("traceparent"))
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithTracerProvider(provider),
+		graphql.WithTextMapPropagator(propagation.TraceContext{}),
+		graphql.WithRetry(1),
+		graphql.WithRetryBaseDelay(time.Millisecond),
+	)
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	if err := client.Query(context.Background(), &q, nil, graphql.OperationName("GetUser")); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want: 3", len(spans))
+	}
+
+	operation := spans[2]
+	if got, want := operation.Name(), "query GetUser"; got != want {
+		t.Errorf("got span name: %q, want: %q", got, want)
+	}
+	if got := spanAttribute(operation, "graphql.operation.type").AsString(); got != "query" {
+		t.Errorf("got operation type: %q, want: query", got)
+	}
+	if got := spanAttribute(operation, "graphql.operation.name").AsString(); got != "GetUser" {
+		t.Errorf("got operation name: %q, want: GetUser", got)
+	}
+	if got := spanAttribute(operation, "graphql.client.attempts").AsInt64(); got != 2 {
+		t.Errorf("got attempts: %d, want: 2", got)
+	}
+
+	for i, attempt := range spans[:2] {
+		if attempt.Parent().SpanID() != operation.SpanContext().SpanID() {
+			t.Errorf("attempt %d: the parent is not the operation span", i)
+		}
+		if got := spanAttribute(attempt, "graphql.client.attempt").AsInt64(); got != int64(i+1) {
+			t.Errorf("attempt %d: got attempt attribute: %d", i, got)
+		}
+		// the header carries the span id of the attempt.
+		if !strings.Contains(traceparents[i], attempt.SpanContext().SpanID().String()) {
+			t.Errorf("attempt %d: got traceparent: %q, want span id: %s", i, traceparents[i], attempt.SpanContext().SpanID())
+		}
+	}
+	if got := spans[0].Status().Code; got != codes.Error {
+		t.Errorf("got status of the failed attempt: %v, want: Error", got)
+	}
+	if got := spanAttribute(spans[0], "http.response.status_code").AsInt64(); got != http.StatusServiceUnavailable {
+		t.Errorf("got status code: %d, want: 503", got)
+	}
+}
+
+func TestClientOption_WithTracerProvider_graphqlErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"errors": [{"message": "permission denied"}]}`)
+	})
+
+	recorder := tracetest.NewSpanRecorder()
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))),
+	)
+
+	var m struct {
+		DeleteUser bool
+	}
+	if err := client.Mutate(context.Background(), &m, nil); err == nil {
+		t.Fatal("got error: nil, want: permission denied")
+	}
+
+	spans := recorder.Ended()
+	operation := spans[len(spans)-1]
+	if got, want := operation.Name(), "mutation"; got != want {
+		t.Errorf("got span name: %q, want: %q", got, want)
+	}
+	if got := operation.Status(); got.Code != codes.Error || got.Description != "permission denied" {
+		t.Errorf("got status: %+v, want: Error permission denied", got)
+	}
+}
+
+func TestSubscription_WithTracerProvider(t *testing.T) {
+	var traceparent atomic.Value
+	server := newMultipartSubscriptionServer(t, []string{
+		`{"payload": {"data": {"helloSaid": {"msg": "hello 0"}}}}`,
+	})
+	server.Config.Handler = func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			traceparent.Store(r.Header.Get("traceparent"))
+			next.ServeHTTP(w, r)
+		})
+	}(server.Config.Handler)
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	client := graphql.NewSubscriptionClient(server.URL).
+		WithProtocol(graphql.MultipartHTTP).
+		WithSyncMode(true).
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))).
+		WithTextMapPropagator(propagation.TraceContext{})
+
+	id, err := client.Subscribe(&helloSaidSubscription{}, nil, func(data []byte, err error) error {
+		return err
+	}, graphql.OperationName("HelloSaid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Run()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription timeout")
+	}
+
+	var subscription, connect sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "subscription HelloSaid":
+			subscription = span
+		case "subscription connect":
+			connect = span
+		}
+	}
+	if subscription == nil || connect == nil {
+		t.Fatalf("got spans: %v, want the subscription and connect spans", recorder.Ended())
+	}
+
+	if got := spanAttribute(subscription, "graphql.subscription.id").AsString(); got != id {
+		t.Errorf("got subscription id: %q, want: %q", got, id)
+	}
+	var events []string
+	for _, event := range subscription.Events() {
+		for _, attr := range event.Attributes {
+			events = append(events, event.Name+" "+attr.Value.AsString())
+		}
+	}
+	if strings.Join(events, ",") != "message next,message complete" {
+		t.Errorf("got events: %v, want: [message next message complete]", events)
+	}
+
+	got, _ := traceparent.Load().(string)
+	if !strings.Contains(got, connect.SpanContext().TraceID().String()) {
+		t.Errorf("got traceparent: %q, want trace id: %s", got, connect.SpanContext().TraceID())
+	}
+}
@@ -17,7 +17,8 @@ type GraphQLType interface {
 // GraphQLRequestPayload represents the graphql JSON-encoded request body
 // https://graphql.org/learn/serving-over-http/#post-request
 type GraphQLRequestPayload struct {
-	Query         string         `json:"query"`
+	Query         string         `json:"query,omitempty"`
 	Variables     map[string]any `json:"variables,omitempty"`
 	OperationName string         `json:"operationName,omitempty"`
+	Extensions    map[string]any `json:"extensions,omitempty"`
 }
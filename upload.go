@@ -0,0 +1,235 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Upload represents a file which is sent with the [GraphQL multipart request] specification.
+// It can be used anywhere in the variables map, e.g. a variable value, a field of an input object or a list item.
+//
+// [GraphQL multipart request]: https://github.com/jaydenseric/graphql-multipart-request-spec
+type Upload struct {
+	// FileName is the name of the file in the form part.
+	FileName string
+	// ContentType is the MIME type of the file, defaults to application/octet-stream.
+	ContentType string
+	// File is the content of the file.
+	File io.Reader
+}
+
+// GetGraphQLType implements the GraphQLType interface.
+func (u Upload) GetGraphQLType() string {
+	return "Upload"
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// Files are replaced by null in the operations part, the server fills them with the content of the file parts.
+func (u Upload) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+var uploadType = reflect.TypeOf(Upload{})
+
+// uploadFile is a file in the variables with its object path in the operations part, e.g. variables.input.files.0.
+type uploadFile struct {
+	path   string
+	upload *Upload
+}
+
+// findUploads walks through the variables and returns all files in a deterministic order.
+func findUploads(variables map[string]any) []uploadFile {
+	if len(variables) == 0 {
+		return nil
+	}
+
+	var files []uploadFile
+
+	walkUploads(reflect.ValueOf(variables), "variables", &files)
+
+	return files
+}
+
+func walkUploads(v reflect.Value, path string, files *[]uploadFile) {
+	if !v.IsValid() {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+
+		walkUploads(v.Elem(), path, files)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+
+		keys := v.MapKeys()
+		// sort keys to produce deterministic form parts.
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+
+		for _, key := range keys {
+			walkUploads(v.MapIndex(key), path+"."+key.String(), files)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return
+		}
+
+		for i := 0; i < v.Len(); i++ {
+			walkUploads(v.Index(i), path+"."+strconv.Itoa(i), files)
+		}
+	case reflect.Struct:
+		if v.Type() == uploadType {
+			upload := v.Interface().(Upload)
+			*files = append(*files, uploadFile{path: path, upload: &upload})
+
+			return
+		}
+
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name := jsonFieldName(field)
+			if name == "" {
+				continue
+			}
+
+			walkUploads(v.Field(i), path+"."+name, files)
+		}
+	default:
+	}
+}
+
+// jsonFieldName returns the name of the struct field in the JSON encoded object, or empty if the field is ignored.
+func jsonFieldName(field reflect.StructField) string {
+	tag, ok := field.Tag.Lookup("json")
+	if !ok {
+		return field.Name
+	}
+
+	name, _, _ := strings.Cut(tag, ",")
+
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	default:
+		return name
+	}
+}
+
+// sendMultipartRequest sends the request payload and files with the multipart/form-data body.
+// The body is buffered in memory so the request can be retried.
+func (c *Client) sendMultipartRequest(
+	ctx context.Context,
+	op operationType,
+	payload GraphQLRequestPayload,
+	files []uploadFile,
+	header http.Header,
+) *rawGraphQLResult {
+	var buf bytes.Buffer
+
+	contentType, err := writeMultipartBody(&buf, payload, files)
+	if err != nil {
+		return &rawGraphQLResult{
+			Errors: Errors{newError(ErrGraphQLEncode, err)},
+		}
+	}
+
+	return c.doHttpRequest(ctx, graphqlHTTPRequest{
+		method:      http.MethodPost,
+		url:         c.url,
+		contentType: contentType,
+		header:      header,
+		body:        bytes.NewReader(buf.Bytes()),
+		op:          op,
+	})
+}
+
+// writeMultipartBody writes the operations, map and file parts to w and returns the content type of the body.
+func writeMultipartBody(w io.Writer, payload GraphQLRequestPayload, files []uploadFile) (string, error) {
+	writer := multipart.NewWriter(w)
+
+	operations, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	if err := writer.WriteField("operations", string(operations)); err != nil {
+		return "", err
+	}
+
+	fileMap := make(map[string][]string, len(files))
+	for i, file := range files {
+		fileMap[strconv.Itoa(i)] = []string{file.path}
+	}
+
+	mapPart, err := json.Marshal(fileMap)
+	if err != nil {
+		return "", err
+	}
+
+	if err := writer.WriteField("map", string(mapPart)); err != nil {
+		return "", err
+	}
+
+	for i, file := range files {
+		if file.upload.File == nil {
+			return "", fmt.Errorf("%s: the file reader is nil", file.path)
+		}
+
+		contentType := file.upload.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		header := make(textproto.MIMEHeader)
+		header.Set(
+			"Content-Disposition",
+			fmt.Sprintf(`form-data; name="%d"; filename="%s"`, i, escapeQuotes(file.upload.FileName)),
+		)
+		header.Set("Content-Type", contentType)
+
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return "", err
+		}
+
+		if _, err := io.Copy(part, file.upload.File); err != nil {
+			return "", fmt.Errorf("%s: %w", file.path, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	return writer.FormDataContentType(), nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
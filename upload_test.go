@@ -0,0 +1,159 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+func TestClient_Mutate_upload(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if err := req.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+
+		var operations struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		if err := json.Unmarshal([]byte(req.FormValue("operations")), &operations); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := operations.Query, "mutation ($files:[Upload!]!$input:UploadInput!){upload(input: $input, files: $files){ok}}"; got != want {
+			t.Errorf("got query: %q, want: %q", got, want)
+		}
+		wantVariables := map[string]any{
+			"files": []any{nil, nil},
+			"input": map[string]any{"name": "avatar", "file": nil},
+		}
+		if !reflect.DeepEqual(operations.Variables, wantVariables) {
+			t.Errorf("got variables: %v, want: %v", operations.Variables, wantVariables)
+		}
+
+		var fileMap map[string][]string
+		if err := json.Unmarshal([]byte(req.FormValue("map")), &fileMap); err != nil {
+			t.Fatal(err)
+		}
+		wantMap := map[string][]string{
+			"0": {"variables.files.0"},
+			"1": {"variables.files.1"},
+			"2": {"variables.input.file"},
+		}
+		if !reflect.DeepEqual(fileMap, wantMap) {
+			t.Errorf("got map: %v, want: %v", fileMap, wantMap)
+		}
+
+		for key, want := range map[string]string{"0": "a.txt:first", "1": "b.txt:second", "2": "avatar.png:image"} {
+			file, header, err := req.FormFile(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, _ := io.ReadAll(file)
+			if got := header.Filename + ":" + string(content); got != want {
+				t.Errorf("got file %s: %q, want: %q", key, got, want)
+			}
+		}
+		if got := req.MultipartForm.File["2"][0].Header.Get("Content-Type"); got != "image/png" {
+			t.Errorf("got content type: %q, want: image/png", got)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"upload": {"ok": true}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	type UploadInput struct {
+		Name string         `json:"name"`
+		File graphql.Upload `json:"file"`
+	}
+
+	var m struct {
+		Upload struct {
+			OK bool
+		} `graphql:"upload(input: $input, files: $files)"`
+	}
+	variables := map[string]any{
+		"input": UploadInput{
+			Name: "avatar",
+			File: graphql.Upload{FileName: "avatar.png", ContentType: "image/png", File: strings.NewReader("image")},
+		},
+		"files": []graphql.Upload{
+			{FileName: "a.txt", File: strings.NewReader("first")},
+			{FileName: "b.txt", File: strings.NewReader("second")},
+		},
+	}
+
+	if err := client.Mutate(context.Background(), &m, variables); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Upload.OK {
+		t.Error("got m.Upload.OK: false, want: true")
+	}
+}
+
+func TestClient_Mutate_uploadPersistedQuery(t *testing.T) {
+	var requests int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		if err := req.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+
+		var operations struct {
+			Query string `json:"query"`
+		}
+		if err := json.Unmarshal([]byte(req.FormValue("operations")), &operations); err != nil {
+			t.Fatal(err)
+		}
+		if operations.Query == "" {
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, `{"errors": [{"message": "PersistedQueryNotFound"}]}`)
+
+			return
+		}
+
+		file, _, err := req.FormFile("0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if content, _ := io.ReadAll(file); string(content) != "hello" {
+			t.Errorf("got file: %q, want: hello", content)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"uploadAvatar": {"ok": true}}}`)
+	})
+	client := graphql.NewClient(
+		"/graphql",
+		&http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithAutomaticPersistedQueries(true),
+	)
+
+	var m struct {
+		UploadAvatar struct {
+			OK bool
+		} `graphql:"uploadAvatar(file: $file)"`
+	}
+	variables := map[string]any{
+		"file": graphql.Upload{FileName: "avatar.png", File: strings.NewReader("hello")},
+	}
+
+	// the file is sent once with the full query, because it can't be read again.
+	if err := client.Mutate(context.Background(), &m, variables); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want: 1", requests)
+	}
+	if !m.UploadAvatar.OK {
+		t.Error("got m.UploadAvatar.OK: false, want: true")
+	}
+}